@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key so keys are recognisable in the
+// Authorization header and in leaked-secret scanners.
+const APIKeyPrefix = "dbms_"
+
+// GenerateAPIKey returns a new API key, the short identifier embedded in it
+// and the hash that should be persisted. The full key is only ever shown to
+// its creator.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashToken(key), nil
+}
+
+// IsAPIKey reports whether a bearer credential looks like an API key rather
+// than an access token.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
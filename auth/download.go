@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+)
+
+// TokenTypeDownload marks signed attachment download links.
+const TokenTypeDownload = "download"
+
+// DownloadClaims authorize fetching one variant of one attachment until
+// ExpiresAt. Subject is the user the link was issued to.
+type DownloadClaims struct {
+	Subject    string `json:"sub"`
+	Attachment int64  `json:"att"`
+	Variant    string `json:"var"`
+	Type       string `json:"typ"`
+	IssuedAt   int64  `json:"iat"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// DownloadURLTTL is how long signed download links remain valid.
+func DownloadURLTTL() time.Duration {
+	return config.Duration("ATTACHMENT_URL_TTL", 15*time.Minute)
+}
+
+// IssueDownloadToken signs a short-lived token for one attachment variant.
+func IssueDownloadToken(subject string, attachmentID int64, variant string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(DownloadURLTTL())
+	token, err := Sign(DownloadClaims{
+		Subject:    subject,
+		Attachment: attachmentID,
+		Variant:    variant,
+		Type:       TokenTypeDownload,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseDownloadToken verifies a token from IssueDownloadToken.
+func ParseDownloadToken(token string) (*DownloadClaims, error) {
+	var claims DownloadClaims
+	if err := Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeDownload {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
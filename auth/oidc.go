@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+)
+
+var ErrOIDCDisabled = errors.New("oidc is not configured")
+
+// OIDCConfig holds the relying-party settings for the campus identity
+// provider.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCConfigFromEnv reads OIDC_* settings. It returns ErrOIDCDisabled when
+// no issuer or client ID is configured.
+func OIDCConfigFromEnv() (OIDCConfig, error) {
+	cfg := OIDCConfig{
+		Issuer:       strings.TrimSuffix(config.String("OIDC_ISSUER", ""), "/"),
+		ClientID:     config.String("OIDC_CLIENT_ID", ""),
+		ClientSecret: config.String("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  config.String("OIDC_REDIRECT_URL", ""),
+		Scopes:       config.List("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return OIDCConfig{}, ErrOIDCDisabled
+	}
+	if cfg.RedirectURL == "" {
+		return OIDCConfig{}, errors.New("OIDC_REDIRECT_URL is required when OIDC is enabled")
+	}
+	return cfg, nil
+}
+
+// IDTokenClaims are the ID token claims used to provision and link users.
+type IDTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// audience accepts the aud claim as either a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider implements the authorization-code flow with PKCE against a
+// provider discovered from its issuer URL.
+type OIDCProvider struct {
+	Config OIDCConfig
+	Client *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcMetadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// NewOIDCProvider returns a provider for cfg. Discovery happens lazily on
+// first use so the server can boot while the identity provider is down.
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		Config: cfg,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthCodeURL returns the provider URL to send the browser to.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.Config.ClientID)
+	values.Set("redirect_uri", p.Config.RedirectURL)
+	values.Set("scope", strings.Join(p.Config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token
+// claims.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDTokenClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+
+	return p.verifyIDToken(ctx, tokenResponse.IDToken, nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported id_token algorithm %q", header.Alg)
+	}
+
+	key, err := p.publicKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims IDTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.Config.Issuer:
+		return nil, errors.New("id_token issuer mismatch")
+	case !slices.Contains(claims.Audience, p.Config.ClientID):
+		return nil, errors.New("id_token audience mismatch")
+	case time.Now().Unix() >= claims.ExpiresAt:
+		return nil, ErrExpiredToken
+	case claims.Nonce != nonce:
+		return nil, errors.New("id_token nonce mismatch")
+	case claims.Subject == "":
+		return nil, errors.New("id_token has no subject")
+	}
+
+	return &claims, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata oidcMetadata
+	if err := p.doJSON(req, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != p.Config.Issuer {
+		return nil, fmt.Errorf("oidc discovery returned issuer %q, expected %q", metadata.Issuer, p.Config.Issuer)
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// publicKey returns the signing key for kid, refetching the key set once when
+// the key is unknown so provider key rotation is picked up.
+func (p *OIDCProvider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.lookupKey(kid)
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.doJSON(req, &jwks); err != nil {
+		return nil, fmt.Errorf("jwks fetch failed: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	key, ok = p.lookupKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds kid in the cached key set. A token without a kid matches
+// when the provider publishes exactly one key. Callers must hold p.mu.
+func (p *OIDCProvider) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, dst any) error {
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, dst)
+}
+
+// OIDCState is the per-login state kept in a signed cookie between the
+// redirect to the provider and the callback.
+type OIDCState struct {
+	Type      string `json:"typ"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewOIDCState generates random state, nonce and PKCE verifier values and
+// returns them along with the signed cookie value that carries them.
+func NewOIDCState(ttl time.Duration) (*OIDCState, string, error) {
+	values := make([]string, 3)
+	for i := range values {
+		value, _, err := NewOpaqueToken()
+		if err != nil {
+			return nil, "", err
+		}
+		values[i] = value
+	}
+
+	state := &OIDCState{
+		Type:      TokenTypeOIDCState,
+		State:     values[0],
+		Nonce:     values[1],
+		Verifier:  values[2],
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	signed, err := Sign(state)
+	if err != nil {
+		return nil, "", err
+	}
+	return state, signed, nil
+}
+
+// ParseOIDCState verifies a cookie value from NewOIDCState.
+func ParseOIDCState(token string) (*OIDCState, error) {
+	var state OIDCState
+	if err := Verify(token, &state); err != nil {
+		return nil, err
+	}
+	if state.Type != TokenTypeOIDCState {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= state.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &state, nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms. The algorithm is recorded in every stored hash:
+// bcrypt hashes use the "$2a$<cost>$" format and argon2id hashes the PHC
+// "$argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<key>" format.
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownPasswordHash = errors.New("unrecognised password hash format")
+
+// PasswordPolicy describes how new password hashes are produced.
+type PasswordPolicy struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2id parameters. Memory is in KiB.
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+	Argon2KeyLen  uint32
+	Argon2SaltLen uint32
+}
+
+// DefaultPasswordPolicy is the policy used by HashPassword. It is set by
+// InitPasswordPolicy.
+var DefaultPasswordPolicy = PasswordPolicy{
+	Algorithm:     PasswordAlgorithmBcrypt,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Time:    2,
+	Argon2Memory:  19 * 1024,
+	Argon2Threads: 1,
+	Argon2KeyLen:  32,
+	Argon2SaltLen: 16,
+}
+
+// InitPasswordPolicy loads the policy from PASSWORD_HASH_ALGORITHM,
+// BCRYPT_COST and the ARGON2_* settings.
+func InitPasswordPolicy() error {
+	policy := DefaultPasswordPolicy
+	policy.Algorithm = strings.ToLower(config.String("PASSWORD_HASH_ALGORITHM", policy.Algorithm))
+	policy.BcryptCost = config.Int("BCRYPT_COST", policy.BcryptCost)
+	argonTime := config.Int("ARGON2_TIME", int(policy.Argon2Time))
+	argonMemory := config.Int("ARGON2_MEMORY_KIB", int(policy.Argon2Memory))
+	argonThreads := config.Int("ARGON2_THREADS", int(policy.Argon2Threads))
+
+	switch policy.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		if policy.BcryptCost < bcrypt.MinCost || policy.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordAlgorithmArgon2id:
+		// Checked before narrowing so out-of-range values cannot wrap into
+		// valid ones.
+		if argonTime < 1 || int64(argonTime) > math.MaxUint32 {
+			return fmt.Errorf("ARGON2_TIME must be between 1 and %d", uint32(math.MaxUint32))
+		}
+		if argonThreads < 1 || argonThreads > math.MaxUint8 {
+			return fmt.Errorf("ARGON2_THREADS must be between 1 and %d", math.MaxUint8)
+		}
+		if argonMemory < 8*argonThreads || int64(argonMemory) > math.MaxUint32 {
+			return errors.New("ARGON2_MEMORY_KIB must be at least 8 per thread")
+		}
+		policy.Argon2Time = uint32(argonTime)
+		policy.Argon2Memory = uint32(argonMemory)
+		policy.Argon2Threads = uint8(argonThreads)
+	default:
+		return fmt.Errorf("unsupported PASSWORD_HASH_ALGORITHM %q", policy.Algorithm)
+	}
+
+	DefaultPasswordPolicy = policy
+	return nil
+}
+
+// HashPassword hashes password with DefaultPasswordPolicy.
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordPolicy.Hash(password)
+}
+
+// VerifyPassword checks password against a stored hash and reports whether
+// the hash is weaker than DefaultPasswordPolicy and should be replaced.
+func VerifyPassword(encoded, password string) (ok, needsRehash bool, err error) {
+	return DefaultPasswordPolicy.Verify(encoded, password)
+}
+
+// Hash hashes password according to the policy.
+func (p PasswordPolicy) Hash(password string) (string, error) {
+	if p.Algorithm == PasswordAlgorithmArgon2id {
+		salt := make([]byte, p.Argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, p.Argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Argon2Memory, p.Argon2Time, p.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify checks password against a hash produced by any supported algorithm
+// and reports whether the hash falls short of the policy.
+func (p PasswordPolicy) Verify(encoded, password string) (ok, needsRehash bool, err error) {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+		weaker := p.Algorithm != PasswordAlgorithmArgon2id ||
+			params.time < p.Argon2Time ||
+			params.memory < p.Argon2Memory ||
+			uint32(len(key)) < p.Argon2KeyLen
+		return true, weaker, nil
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return false, false, ErrUnknownPasswordHash
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return true, p.Algorithm != PasswordAlgorithmBcrypt || cost < p.BcryptCost, nil
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil ||
+		params.time == 0 || params.threads == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast; only their relative strength matters.
+var (
+	testBcrypt = PasswordPolicy{
+		Algorithm:  PasswordAlgorithmBcrypt,
+		BcryptCost: bcrypt.MinCost,
+	}
+	testArgon2id = PasswordPolicy{
+		Algorithm:     PasswordAlgorithmArgon2id,
+		BcryptCost:    bcrypt.MinCost,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+		Argon2KeyLen:  32,
+		Argon2SaltLen: 16,
+	}
+)
+
+func TestInitPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"defaults", nil, false},
+		{"bcrypt cost in range", map[string]string{"BCRYPT_COST": "11"}, false},
+		{"bcrypt cost too low", map[string]string{"BCRYPT_COST": "3"}, true},
+		{"bcrypt cost too high", map[string]string{"BCRYPT_COST": "32"}, true},
+		{"argon2id", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id"}, false},
+		{"argon2id upper case", map[string]string{"PASSWORD_HASH_ALGORITHM": "ARGON2ID"}, false},
+		{"argon2id max threads", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_THREADS": "255", "ARGON2_MEMORY_KIB": "2040"}, false},
+		{"argon2id zero threads", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_THREADS": "0"}, true},
+		{"argon2id threads wrap to zero", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_THREADS": "256"}, true},
+		{"argon2id threads wrap to one", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_THREADS": "257"}, true},
+		{"argon2id negative time", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_TIME": "-1"}, true},
+		{"argon2id memory below 8 per thread", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_THREADS": "4", "ARGON2_MEMORY_KIB": "31"}, true},
+		{"argon2id memory wraps", map[string]string{"PASSWORD_HASH_ALGORITHM": "argon2id", "ARGON2_MEMORY_KIB": "4294967304"}, true},
+		{"unknown algorithm", map[string]string{"PASSWORD_HASH_ALGORITHM": "md5"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := DefaultPasswordPolicy
+			t.Cleanup(func() { DefaultPasswordPolicy = previous })
+			for _, key := range []string{"PASSWORD_HASH_ALGORITHM", "BCRYPT_COST", "ARGON2_TIME", "ARGON2_MEMORY_KIB", "ARGON2_THREADS"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			err := InitPasswordPolicy()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InitPasswordPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && DefaultPasswordPolicy != previous {
+				t.Fatal("a rejected policy replaced DefaultPasswordPolicy")
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyVerify(t *testing.T) {
+	strongerBcrypt := testBcrypt
+	strongerBcrypt.BcryptCost++
+	strongerArgon2id := testArgon2id
+	strongerArgon2id.Argon2Time++
+
+	tests := []struct {
+		name            string
+		hashWith        PasswordPolicy
+		verifyWith      PasswordPolicy
+		password        string
+		wantOK          bool
+		wantNeedsRehash bool
+	}{
+		{"bcrypt match", testBcrypt, testBcrypt, "secret", true, false},
+		{"bcrypt mismatch", testBcrypt, testBcrypt, "wrong", false, false},
+		{"bcrypt cost raised", testBcrypt, strongerBcrypt, "secret", true, true},
+		{"bcrypt cost lowered", strongerBcrypt, testBcrypt, "secret", true, false},
+		{"argon2id match", testArgon2id, testArgon2id, "secret", true, false},
+		{"argon2id mismatch", testArgon2id, testArgon2id, "wrong", false, false},
+		{"argon2id time raised", testArgon2id, strongerArgon2id, "secret", true, true},
+		{"bcrypt to argon2id", testBcrypt, testArgon2id, "secret", true, true},
+		{"argon2id to bcrypt", testArgon2id, testBcrypt, "secret", true, true},
+		{"mismatch never asks for rehash", testBcrypt, testArgon2id, "wrong", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.hashWith.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+
+			ok, needsRehash, err := tt.verifyWith.Verify(encoded, tt.password)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Fatalf("Verify() = (%v, %v), want (%v, %v)", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyVerifyRejectsMalformedHashes(t *testing.T) {
+	tests := []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$not base64$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+	}
+
+	for _, encoded := range tests {
+		if _, _, err := testArgon2id.Verify(encoded, "secret"); !errors.Is(err, ErrUnknownPasswordHash) {
+			t.Errorf("Verify(%q) error = %v, want ErrUnknownPasswordHash", encoded, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/google/uuid"
+)
+
+// Token types carried in the "typ" claim so a token minted for one purpose
+// cannot be replayed for another.
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+	TokenTypeOIDCState          = "oidc_state"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims is the payload of tokens signed by this package.
+type Claims struct {
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid,omitempty"`
+	Type      string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UserID parses the subject claim as a user UUID.
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// Session parses the session claim as a session UUID.
+func (c *Claims) Session() (uuid.UUID, error) {
+	return uuid.Parse(c.SessionID)
+}
+
+var (
+	secretOnce sync.Once
+	secret     []byte
+)
+
+func signingSecret() []byte {
+	secretOnce.Do(func() {
+		if value := config.String("JWT_SECRET", ""); value != "" {
+			secret = []byte(value)
+			return
+		}
+		slog.Warn("JWT_SECRET is not set; using an ephemeral secret, tokens will not survive a restart")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("failed to generate signing secret: %v", err))
+		}
+	})
+	return secret
+}
+
+// AccessTokenTTL is how long issued access tokens remain valid.
+func AccessTokenTTL() time.Duration {
+	return config.Duration("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL is how long issued refresh tokens remain valid.
+func RefreshTokenTTL() time.Duration {
+	return config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// IssueAccessToken signs a short-lived access token for the given user and
+// session.
+func IssueAccessToken(userID, sessionID uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL())
+	token, err := Sign(Claims{
+		Subject:   userID.String(),
+		SessionID: sessionID.String(),
+		Type:      TokenTypeAccess,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseAccessToken verifies an access token and returns its claims.
+func ParseAccessToken(token string) (*Claims, error) {
+	var claims Claims
+	if err := Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// TwoFactorChallengeTTL is how long a user has to enter their second factor
+// after a successful password check.
+func TwoFactorChallengeTTL() time.Duration {
+	return config.Duration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute)
+}
+
+// IssueTwoFactorChallenge signs a token proving the user passed the password
+// step of sign-in.
+func IssueTwoFactorChallenge(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	return Sign(Claims{
+		Subject:   userID.String(),
+		Type:      TokenTypeTwoFactorChallenge,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(TwoFactorChallengeTTL()).Unix(),
+	})
+}
+
+// ParseTwoFactorChallenge verifies a token from IssueTwoFactorChallenge.
+func ParseTwoFactorChallenge(token string) (*Claims, error) {
+	var claims Claims
+	if err := Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeTwoFactorChallenge {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Sign encodes claims as an HS256 JWT using the server secret.
+func Sign(claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + signature(signingInput), nil
+}
+
+// Verify checks the signature of an HS256 JWT and decodes its payload into
+// dst. Expiry is left to the caller since claim layouts differ.
+func Verify(token string, dst any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return ErrInvalidToken
+	}
+	expected := signature(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, dst); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func signature(signingInput string) string {
+	mac := hmac.New(sha256.New, signingSecret())
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewOpaqueToken returns a random URL-safe token along with the hash that
+// should be persisted in its place.
+func NewOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 digest used to store opaque tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 appendix B, "12345678901234567890",
+// in base32. The codes below are the last six digits of its test vectors.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"rfc vector 59", rfc6238Secret, "287082", 59, 1, true},
+		{"rfc vector 1111111109", rfc6238Secret, "081804", 1111111109, 37037036, true},
+		{"rfc vector 1234567890", rfc6238Secret, "005924", 1234567890, 41152263, true},
+		{"one step late", rfc6238Secret, "287082", 89, 1, true},
+		{"one step early", rfc6238Secret, "287082", 29, 1, true},
+		{"two steps late", rfc6238Secret, "287082", 90, 0, false},
+		{"two steps early", rfc6238Secret, "081804", 1111111109 - 60, 0, false},
+		{"surrounding spaces", rfc6238Secret, " 287082 ", 59, 1, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", 59, 1, true},
+		{"wrong code", rfc6238Secret, "287083", 59, 0, false},
+		{"too short", rfc6238Secret, "28708", 59, 0, false},
+		{"eight digits", rfc6238Secret, "94287082", 59, 0, false},
+		{"invalid secret", "not base32!", "287082", 59, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.now, 0))
+			if step != tt.wantStep || ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP(%q at %d) = (%d, %v), want (%d, %v)", tt.code, tt.now, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestValidateTOTPReplay plays the caller's side of replay protection: a code
+// is only accepted when its step is newer than the last one used, as the
+// totp_last_step check in routes does.
+func TestValidateTOTPReplay(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		step   int64
+		now    int64
+		wantOK bool
+	}{
+		{"first use", 1, 59, true},
+		{"same code again", 1, 75, false},
+		{"next code", 2, 75, true},
+		{"earlier code still within skew", 1, 80, false},
+		{"later code", 3, 95, true},
+	}
+
+	var lastStep int64
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, tt.step), time.Unix(tt.now, 0))
+		if !ok || step != tt.step {
+			t.Fatalf("%s: ValidateTOTP = (%d, %v), want (%d, true)", tt.name, step, ok, tt.step)
+		}
+		if accepted := step > lastStep; accepted != tt.wantOK {
+			t.Fatalf("%s: accepted = %v, want %v", tt.name, accepted, tt.wantOK)
+		}
+		if step > lastStep {
+			lastStep = step
+		}
+	}
+}
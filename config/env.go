@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the environment variable for key or fallback when unset.
+func String(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Int returns the environment variable for key parsed as an int, or fallback
+// when unset or invalid.
+func Int(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// Duration returns the environment variable for key parsed with
+// time.ParseDuration, or fallback when unset or invalid.
+func Duration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// Bool returns the environment variable for key interpreted as a boolean, or
+// fallback when unset or not one of the usual true/false spellings.
+func Bool(key string, fallback bool) bool {
+	value := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
+	switch value {
+	case "true", "t", "1", "yes", "y", "on", "enabled":
+		return true
+	case "false", "f", "0", "no", "n", "off", "disabled":
+		return false
+	default:
+		return fallback
+	}
+}
+
+// List returns the environment variable for key split on commas, with blank
+// entries dropped, or fallback when unset.
+func List(key string, fallback []string) []string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -5,7 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	"github.com/adii2ma/dbms-backend/migrations"
@@ -38,7 +38,7 @@ func InitDB() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Database connection established successfully")
+	slog.Info("database connection established")
 	return nil
 }
 
@@ -61,7 +61,7 @@ func RunMigrations(ctx context.Context) error {
 
 	unapplied := ms.Unapplied()
 	if len(unapplied) == 0 {
-		log.Println("No new migrations to run")
+		slog.Info("no new migrations to run")
 		return nil
 	}
 
@@ -71,7 +71,7 @@ func RunMigrations(ctx context.Context) error {
 	}
 
 	if group != nil {
-		log.Printf("Applied migration group %s", group)
+		slog.Info("applied migrations", "group", group.String())
 	}
 
 	return nil
@@ -0,0 +1,153 @@
+// Package logging configures the application's structured logger and keeps
+// sensitive values such as passwords and tokens out of log output.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/config"
+)
+
+// Redacted replaces the value of every redacted field.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are redacted when LOG_REDACT_FIELDS is unset.
+var DefaultRedactFields = []string{
+	"password", "current_password", "new_password",
+	"token", "access_token", "refresh_token", "challenge_token",
+	"code", "recovery_code", "recovery_codes", "secret", "key",
+	"email", "phone",
+}
+
+// Init installs the default slog logger. LOG_LEVEL selects debug, info, warn
+// or error and LOG_FORMAT selects json or text. Output from the standard log
+// package is routed through the same handler.
+func Init() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.String("LOG_LEVEL", "info"))); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+
+	format := strings.ToLower(config.String("LOG_FORMAT", "json"))
+	if format != "json" && format != "text" {
+		return fmt.Errorf("unsupported LOG_FORMAT %q", format)
+	}
+
+	slog.SetDefault(New(os.Stdout, level, format, RedactFields()))
+	return nil
+}
+
+// New returns a logger writing to w that redacts attributes named in redact.
+func New(w io.Writer, level slog.Level, format string, redact []string) *slog.Logger {
+	r := NewRedactor(redact)
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if r.Matches(attr.Key) {
+				return slog.String(attr.Key, Redacted)
+			}
+			return attr
+		},
+	}
+
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// RedactFields returns the field names from LOG_REDACT_FIELDS, or
+// DefaultRedactFields when unset.
+func RedactFields() []string {
+	return config.List("LOG_REDACT_FIELDS", DefaultRedactFields)
+}
+
+// Redactor masks configured field names in JSON documents, query strings
+// and log attributes. Names match case-insensitively.
+type Redactor struct {
+	fields map[string]struct{}
+}
+
+// NewRedactor returns a Redactor for the given field names.
+func NewRedactor(fields []string) *Redactor {
+	r := &Redactor{fields: make(map[string]struct{}, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(strings.TrimSpace(field))] = struct{}{}
+	}
+	return r
+}
+
+// Matches reports whether name is a redacted field.
+func (r *Redactor) Matches(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+// JSON returns body with the values of redacted fields replaced at any depth.
+// Bodies that are not valid JSON are dropped entirely rather than logged.
+func (r *Redactor) JSON(body []byte) string {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "[unparseable body omitted]"
+	}
+	redacted, err := json.Marshal(r.value(doc))
+	if err != nil {
+		return "[unparseable body omitted]"
+	}
+	return string(redacted)
+}
+
+func (r *Redactor) value(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if r.Matches(key) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = r.value(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Query returns the encoded query string with redacted parameter values
+// replaced.
+func (r *Redactor) Query(values url.Values) string {
+	for key := range values {
+		if r.Matches(key) {
+			values[key] = []string{Redacted}
+		}
+	}
+	return values.Encode()
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or the default
+// logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactorJSON(t *testing.T) {
+	r := NewRedactor([]string{"password", " Token ", "email"})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"top level", `{"email":"a@b.c","name":"A"}`, `{"email":"[REDACTED]","name":"A"}`},
+		{"case insensitive", `{"PassWord":"x","TOKEN":"y"}`, `{"PassWord":"[REDACTED]","TOKEN":"[REDACTED]"}`},
+		{"nested object", `{"user":{"password":"x","id":1}}`, `{"user":{"id":1,"password":"[REDACTED]"}}`},
+		{"inside array", `[{"token":"x"},{"other":"y"}]`, `[{"token":"[REDACTED]"},{"other":"y"}]`},
+		{"whole subtree", `{"password":{"old":"x","new":"y"}}`, `{"password":"[REDACTED]"}`},
+		{"nothing to redact", `{"status":"active"}`, `{"status":"active"}`},
+		{"scalar", `"password"`, `"password"`},
+		{"invalid json", `password=hunter2`, "[unparseable body omitted]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.JSON([]byte(tt.body)); got != tt.want {
+				t.Fatalf("JSON(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorQuery(t *testing.T) {
+	r := NewRedactor(DefaultRedactFields)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"token=abc&type=cleaning", "token=%5BREDACTED%5D&type=cleaning"},
+		{"Code=1&code=2&state=s", "Code=%5BREDACTED%5D&code=%5BREDACTED%5D&state=s"},
+		{"email=a%40b.c&email=d%40e.f", "email=%5BREDACTED%5D"},
+		{"room_id=4", "room_id=4"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		values, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+		}
+		if got := r.Query(values); got != tt.want {
+			t.Errorf("Query(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewRedactsAttributes(t *testing.T) {
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, slog.LevelInfo, format, []string{"password", "email"})
+
+			logger.Info("signin", "email", "a@b.c", "Password", "hunter2", "user_id", 7)
+			logger.Debug("dropped", "level", "debug")
+
+			out := buf.String()
+			for _, leaked := range []string{"a@b.c", "hunter2", "dropped"} {
+				if strings.Contains(out, leaked) {
+					t.Errorf("log output contains %q: %s", leaked, out)
+				}
+			}
+			if !strings.Contains(out, Redacted) || !strings.Contains(out, "user_id") {
+				t.Errorf("log output missing redacted or plain attributes: %s", out)
+			}
+			if format == "json" {
+				var line map[string]any
+				if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+					t.Fatalf("output is not one JSON line: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer used by the HTTP handlers. It is set by Init.
+var Default Mailer = LogMailer{}
+
+// Init selects the mailer from the MAILER environment variable. Supported
+// values are "log" (the default) and "file", which writes each message to
+// MAILER_DIR.
+func Init() error {
+	switch strings.ToLower(config.String("MAILER", "log")) {
+	case "log":
+		Default = LogMailer{}
+	case "file":
+		dir := config.String("MAILER_DIR", "mail")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create mail directory: %w", err)
+		}
+		Default = FileMailer{Dir: dir}
+	default:
+		return fmt.Errorf("unsupported MAILER %q", config.String("MAILER", ""))
+	}
+	return nil
+}
+
+// LogMailer writes messages to the application log. It is meant for local
+// development only.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "outgoing mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// FileMailer writes each message to its own file in Dir so links can be
+// opened during local development.
+type FileMailer struct {
+	Dir string
+}
+
+func (m FileMailer) Send(_ context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%s.txt", time.Now().UTC().Format("20060102T150405.000000000"), sanitize(msg.To))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(content), 0o600)
+}
+
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
@@ -2,44 +2,74 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/adii2ma/dbms-backend/auth"
 	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/adii2ma/dbms-backend/mailer"
+	"github.com/adii2ma/dbms-backend/models"
 	"github.com/adii2ma/dbms-backend/routes"
+	"github.com/adii2ma/dbms-backend/storage"
+	"github.com/adii2ma/dbms-backend/workers"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file loaded: %v", err)
+	envErr := godotenv.Load()
+
+	if err := logging.Init(); err != nil {
+		fatal("failed to initialize logging", err)
+	}
+	if envErr != nil {
+		slog.Info("no .env file loaded", "error", envErr)
 	}
 
 	// Initialize database
 	if err := database.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("failed to initialize database", err)
 	}
 	defer database.CloseDB()
 
+	if err := auth.InitPasswordPolicy(); err != nil {
+		fatal("invalid password hashing policy", err)
+	}
+
+	if err := mailer.Init(); err != nil {
+		fatal("failed to initialize mailer", err)
+	}
+
+	if err := storage.Init(); err != nil {
+		fatal("failed to initialize blob storage", err)
+	}
+
 	if shouldMigrate() {
 		if err := database.RunMigrations(context.Background()); err != nil {
-			log.Fatalf("Failed to apply migrations: %v", err)
+			fatal("failed to apply migrations", err)
 		}
 	}
 
-	// Initialize Gin router
-	router := gin.Default()
+	// Background workers stop when main returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workers.Start(ctx, database.DB)
+
+	// Initialize Gin router. Requests are logged by routes.LogRequests
+	// instead of Gin's own logger.
+	router := gin.New()
+	router.Use(gin.Recovery(), routes.LogRequests())
 
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // Allow all origins, or specify your frontend URL like "http://localhost:3000"
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -60,13 +90,121 @@ func main() {
 		{
 			auth.POST("/signup", routes.SignUp)
 			auth.POST("/signin", routes.SignIn)
+			auth.POST("/refresh", routes.Refresh)
+			auth.POST("/forgot-password", routes.ForgotPassword)
+			auth.POST("/reset-password", routes.ResetPassword)
+			auth.GET("/verify", routes.VerifyEmail)
+			auth.POST("/verify/resend", routes.RequireAuth(), routes.ResendVerification)
+			auth.POST("/signin/2fa", routes.SignInTwoFactor)
+			auth.GET("/oidc/login", routes.OIDCLogin)
+			auth.GET("/oidc/callback", routes.OIDCCallback)
+			auth.GET("/invite", routes.GetInvitation)
+			auth.POST("/invite/accept", routes.AcceptInvitation)
+			auth.POST("/logout", routes.RequireAuthForTwoFactorSetup(), routes.Logout)
+			auth.GET("/sessions", routes.RequireAuth(), routes.ListSessions)
+			auth.DELETE("/sessions/:id", routes.RequireAuth(), routes.RevokeSession)
+
+			twoFactor := auth.Group("/2fa")
+			{
+				twoFactor.POST("/setup", routes.RequireAuthForTwoFactorSetup(), routes.SetupTwoFactor)
+				twoFactor.POST("/enable", routes.RequireAuthForTwoFactorSetup(), routes.EnableTwoFactor)
+				twoFactor.POST("/disable", routes.RequireAuth(), routes.DisableTwoFactor)
+				twoFactor.POST("/recovery-codes", routes.RequireAuth(), routes.RegenerateRecoveryCodes)
+			}
 		}
 
+		// Request routes also accept API keys holding the matching scope
 		requests := api.Group("/requests")
 		{
-			requests.POST("", routes.CreateRequest)
-			requests.GET("/active", routes.GetActiveRequest)
-			requests.GET("/status", routes.GetRequestStatus)
+			read := routes.RequireAuthOrAPIKey(models.ScopeRequestsRead)
+			write := routes.RequireAuthOrAPIKey(models.ScopeRequestsWrite)
+
+			requests.GET("", read, routes.ListRequests)
+			requests.POST("", write, routes.RequireRole(models.RoleResident, models.RoleWarden, models.RoleAdmin), routes.RequireVerifiedEmail(), routes.CreateRequest)
+			requests.GET("/active", read, routes.GetActiveRequest)
+			requests.GET("/status", read, routes.GetRequestStatus)
+			requests.PATCH("/:id/status", write, routes.UpdateRequestStatus)
+			requests.PATCH("/:id/priority", write, routes.RequireRole(models.RoleCleaner, models.RoleTechnician, models.RoleWarden, models.RoleAdmin), routes.SetRequestPriority)
+			requests.POST("/:id/assign", write, routes.RequireRole(models.RoleCleaner, models.RoleTechnician, models.RoleWarden, models.RoleAdmin), routes.AssignRequest)
+			requests.PATCH("/:id", write, routes.RequireRole(models.RoleResident, models.RoleWarden, models.RoleAdmin), routes.UpdateRequest)
+			requests.GET("/:id/timeline", read, routes.GetRequestTimeline)
+			requests.GET("/:id/confirmation", read, routes.GetRequestConfirmation)
+			requests.POST("/:id/confirm", write, routes.RequireRole(models.RoleResident), routes.ConfirmRequest)
+			requests.POST("/:id/dispute", write, routes.RequireRole(models.RoleResident), routes.DisputeRequest)
+			requests.PUT("/:id/time-windows", write, routes.RequireRole(models.RoleResident, models.RoleWarden, models.RoleAdmin), routes.SetRequestTimeWindows)
+			requests.POST("/:id/no-access", write, routes.RequireRole(models.RoleCleaner, models.RoleTechnician, models.RoleWarden, models.RoleAdmin), routes.RecordNoAccess)
+			requests.GET("/:id/comments", read, routes.ListRequestComments)
+			requests.POST("/:id/comments", write, routes.CreateRequestComment)
+			requests.PATCH("/:id/comments/:commentId", write, routes.UpdateRequestComment)
+			requests.DELETE("/:id/comments/:commentId", write, routes.DeleteRequestComment)
+			requests.GET("/:id/attachments", read, routes.ListRequestAttachments)
+			requests.POST("/:id/attachments", write, routes.UploadRequestAttachment)
+			requests.DELETE("/:id/attachments/:attachmentId", write, routes.DeleteRequestAttachment)
+			requests.GET("/:id/attachments/:attachmentId/download", routes.DownloadRequestAttachment)
+		}
+
+		api.GET("/request-types", routes.RequireAuthOrAPIKey(models.ScopeRequestsRead), routes.ListRequestTypes)
+
+		// Recurring request schedules share the request API key scopes
+		schedules := api.Group("/schedules")
+		{
+			read := routes.RequireAuthOrAPIKey(models.ScopeRequestsRead)
+			write := routes.RequireAuthOrAPIKey(models.ScopeRequestsWrite)
+			manage := routes.RequireRole(models.RoleResident, models.RoleWarden, models.RoleAdmin)
+
+			schedules.GET("", read, routes.ListSchedules)
+			schedules.POST("", write, manage, routes.RequireVerifiedEmail(), routes.CreateSchedule)
+			schedules.GET("/:id", read, routes.GetSchedule)
+			schedules.PATCH("/:id", write, manage, routes.UpdateSchedule)
+			schedules.DELETE("/:id", write, manage, routes.DeleteSchedule)
+		}
+
+		users := api.Group("/users", routes.RequireAuth())
+		{
+			users.GET("/me", routes.GetProfile)
+			users.PATCH("/me", routes.UpdateProfile)
+			users.POST("/me/password", routes.ChangePassword)
+		}
+
+		invitations := api.Group("/invitations", routes.RequireAuth(), routes.RequireRole(models.RoleWarden, models.RoleAdmin))
+		{
+			invitations.POST("", routes.CreateInvitation)
+			invitations.GET("", routes.ListInvitations)
+			invitations.DELETE("/:id", routes.RevokeInvitation)
+		}
+
+		apiKeys := api.Group("/api-keys", routes.RequireAuth())
+		{
+			apiKeys.POST("", routes.CreateAPIKey)
+			apiKeys.GET("", routes.ListAPIKeys)
+			apiKeys.DELETE("/:id", routes.RevokeAPIKey)
+		}
+
+		admin := api.Group("/admin", routes.RequireAuth())
+		{
+			admin.GET("/users", routes.RequireRole(models.RoleAdmin), routes.ListUsers)
+			admin.GET("/users/:id", routes.RequireRole(models.RoleAdmin), routes.GetUser)
+			admin.POST("/users/:id/deactivate", routes.RequireRole(models.RoleAdmin), routes.DeactivateUser)
+			admin.POST("/users/:id/reactivate", routes.RequireRole(models.RoleAdmin), routes.ReactivateUser)
+			admin.PATCH("/users/:id/role", routes.RequireRole(models.RoleAdmin), routes.UpdateUserRole)
+			admin.PATCH("/users/:id/on-call", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.UpdateOnCall)
+			admin.PATCH("/users/:id/room", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.MoveUserRoom)
+			admin.POST("/users/:id/password-reset", routes.RequireRole(models.RoleAdmin), routes.ForcePasswordReset)
+			admin.POST("/users/:id/unlock", routes.RequireRole(models.RoleAdmin), routes.UnlockUser)
+			admin.DELETE("/users/:id/sessions", routes.RequireRole(models.RoleAdmin), routes.RevokeUserSessions)
+			admin.DELETE("/users/:id/2fa", routes.RequireRole(models.RoleAdmin), routes.ResetUserTwoFactor)
+			admin.GET("/security-events", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.ListSecurityEvents)
+			admin.GET("/sla-policies", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.ListSLAPolicies)
+			admin.PUT("/sla-policies/:type/:priority", routes.RequireRole(models.RoleAdmin), routes.UpsertSLAPolicy)
+			admin.DELETE("/sla-policies/:type/:priority", routes.RequireRole(models.RoleAdmin), routes.DeleteSLAPolicy)
+			admin.POST("/request-types", routes.RequireRole(models.RoleAdmin), routes.CreateRequestType)
+			admin.PATCH("/request-types/:name", routes.RequireRole(models.RoleAdmin), routes.UpdateRequestType)
+			admin.GET("/service-hours/:block", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.GetServiceHours)
+			admin.PUT("/service-hours/:block", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.SetServiceHours)
+			admin.GET("/sla-breaches", routes.RequireRole(models.RoleWarden, models.RoleAdmin), routes.ListSLABreaches)
+			admin.POST("/users/:id/api-keys", routes.RequireRole(models.RoleAdmin), routes.CreateServiceAPIKey)
+			admin.GET("/api-keys", routes.RequireRole(models.RoleAdmin), routes.ListAllAPIKeys)
+			admin.DELETE("/api-keys/:id", routes.RequireRole(models.RoleAdmin), routes.AdminRevokeAPIKey)
 		}
 	}
 
@@ -76,9 +214,9 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
+	slog.Info("server starting", "port", port)
 	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		fatal("failed to start server", err)
 	}
 }
 
@@ -90,3 +228,9 @@ func shouldMigrate() bool {
 	value = strings.TrimSpace(strings.ToLower(value))
 	return value == "true" || value == "1" || value == "yes" || value == "y"
 }
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
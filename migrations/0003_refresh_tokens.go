@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS refresh_tokens (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					token_hash TEXT UNIQUE NOT NULL,
+					expires_at TIMESTAMP NOT NULL,
+					revoked_at TIMESTAMP,
+					replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user
+				ON refresh_tokens (user_id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS refresh_tokens`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'resident'
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				DO $$
+				BEGIN
+					IF NOT EXISTS (
+						SELECT 1 FROM pg_constraint WHERE conname = 'users_role_check'
+					) THEN
+						ALTER TABLE users
+							ADD CONSTRAINT users_role_check
+								CHECK (role IN ('resident', 'cleaner', 'technician', 'warden', 'admin'));
+					END IF;
+				END$$
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+				ALTER TABLE users DROP COLUMN IF EXISTS role;
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
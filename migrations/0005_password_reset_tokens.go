@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS password_reset_tokens (
+					id SERIAL PRIMARY KEY,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					token_hash TEXT UNIQUE NOT NULL,
+					expires_at TIMESTAMP NOT NULL,
+					used BOOLEAN NOT NULL DEFAULT false,
+					used_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user
+				ON password_reset_tokens (user_id)
+			`); err != nil {
+				return err
+			}
+
+			// Access tokens issued before this moment are rejected.
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS password_reset_tokens`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP
+			`); err != nil {
+				return err
+			}
+
+			// Accounts created before verification existed keep working.
+			if _, err := db.ExecContext(ctx, `
+				UPDATE users
+				SET email_verified_at = COALESCE(created_at, now())
+				WHERE email_verified_at IS NULL
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS email_verification_tokens (
+					id SERIAL PRIMARY KEY,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					token_hash TEXT UNIQUE NOT NULL,
+					expires_at TIMESTAMP NOT NULL,
+					used_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user
+				ON email_verification_tokens (user_id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS email_verification_tokens`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
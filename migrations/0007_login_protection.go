@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS login_attempts (
+					id BIGSERIAL PRIMARY KEY,
+					email TEXT NOT NULL,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					ip TEXT NOT NULL,
+					success BOOLEAN NOT NULL,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created
+				ON login_attempts (ip, created_at)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS security_events (
+					id BIGSERIAL PRIMARY KEY,
+					type TEXT NOT NULL,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					ip TEXT,
+					details TEXT,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_security_events_created
+				ON security_events (created_at)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS security_events`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS login_attempts`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+				ALTER TABLE users DROP COLUMN IF EXISTS last_failed_login_at;
+				ALTER TABLE users DROP COLUMN IF EXISTS failed_login_count;
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
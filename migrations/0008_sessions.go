@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS sessions (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					user_agent TEXT NOT NULL DEFAULT '',
+					ip TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMP DEFAULT now(),
+					last_seen_at TIMESTAMP DEFAULT now(),
+					expires_at TIMESTAMP NOT NULL,
+					revoked_at TIMESTAMP
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_sessions_user
+				ON sessions (user_id)
+			`); err != nil {
+				return err
+			}
+
+			// Refresh tokens issued before sessions existed cannot be tied to
+			// one, so their holders have to sign in again.
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE refresh_tokens
+					ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES sessions(id) ON DELETE CASCADE
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				UPDATE refresh_tokens
+				SET revoked_at = now()
+				WHERE session_id IS NULL AND revoked_at IS NULL
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS sessions`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+					id SERIAL PRIMARY KEY,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					code_hash TEXT NOT NULL,
+					used_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user
+				ON two_factor_recovery_codes (user_id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS two_factor_recovery_codes`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+				ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+				ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
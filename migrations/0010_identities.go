@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS identities (
+					id SERIAL PRIMARY KEY,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					issuer TEXT NOT NULL,
+					subject TEXT NOT NULL,
+					email TEXT,
+					created_at TIMESTAMP DEFAULT now(),
+					last_login_at TIMESTAMP,
+					CONSTRAINT unique_identity_issuer_subject UNIQUE (issuer, subject)
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_identities_user
+				ON identities (user_id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS identities`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
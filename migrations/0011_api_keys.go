@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS api_keys (
+					id SERIAL PRIMARY KEY,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					name TEXT NOT NULL,
+					kind TEXT NOT NULL DEFAULT 'personal' CHECK (kind IN ('personal', 'service')),
+					prefix TEXT UNIQUE NOT NULL,
+					key_hash TEXT UNIQUE NOT NULL,
+					scopes TEXT[] NOT NULL DEFAULT '{}',
+					expires_at TIMESTAMP,
+					last_used_at TIMESTAMP,
+					revoked_at TIMESTAMP,
+					created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_api_keys_user
+				ON api_keys (user_id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS api_keys`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+				ALTER TABLE room_members ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE room_members DROP COLUMN IF EXISTS active;
+				ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS invitations (
+					id SERIAL PRIMARY KEY,
+					email TEXT NOT NULL,
+					room_id INT NOT NULL,
+					block TEXT NOT NULL,
+					role TEXT NOT NULL DEFAULT 'resident' CHECK (role IN ('resident', 'cleaner', 'technician', 'warden', 'admin')),
+					token_hash TEXT UNIQUE NOT NULL,
+					expires_at TIMESTAMP NOT NULL,
+					invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+					accepted_at TIMESTAMP,
+					accepted_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					revoked_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT now(),
+					CONSTRAINT fk_invitations_room FOREIGN KEY (room_id, block) REFERENCES rooms(id, block) ON DELETE CASCADE
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_invitations_email
+				ON invitations (lower(email))
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS invitations`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			// Keep requests.updated_at current on every update, whichever code
+			// path makes it.
+			if _, err := db.ExecContext(ctx, `
+				CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
+				BEGIN
+					NEW.updated_at = now();
+					RETURN NEW;
+				END;
+				$$ LANGUAGE plpgsql
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				DROP TRIGGER IF EXISTS requests_set_updated_at ON requests;
+				CREATE TRIGGER requests_set_updated_at
+				BEFORE UPDATE ON requests
+				FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				DROP TRIGGER IF EXISTS requests_set_updated_at ON requests;
+				DROP FUNCTION IF EXISTS set_updated_at();
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
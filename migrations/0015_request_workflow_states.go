@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_status_check;
+				ALTER TABLE requests ADD CONSTRAINT requests_status_check
+					CHECK (status IN ('active', 'assigned', 'in_progress', 'on_hold', 'awaiting_parts', 'completed', 'cancelled'));
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests ADD COLUMN IF NOT EXISTS assignee_id UUID REFERENCES users(id) ON DELETE SET NULL;
+				ALTER TABLE requests ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP;
+				CREATE INDEX IF NOT EXISTS idx_requests_assignee ON requests (assignee_id);
+			`); err != nil {
+				return err
+			}
+
+			// A room may have one open request per type, whatever stage it
+			// has reached.
+			if _, err := db.ExecContext(ctx, `
+				DROP INDEX IF EXISTS unique_active_request_per_room_type;
+				CREATE UNIQUE INDEX IF NOT EXISTS unique_open_request_per_room_type
+				ON requests (room_id, type)
+				WHERE status NOT IN ('completed', 'cancelled');
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				DROP INDEX IF EXISTS unique_open_request_per_room_type;
+				UPDATE requests SET status = 'active'
+				WHERE status IN ('assigned', 'in_progress', 'on_hold', 'awaiting_parts');
+				CREATE UNIQUE INDEX IF NOT EXISTS unique_active_request_per_room_type
+				ON requests (room_id, type)
+				WHERE status = 'active';
+				ALTER TABLE requests DROP COLUMN IF EXISTS assigned_at;
+				ALTER TABLE requests DROP COLUMN IF EXISTS assignee_id;
+				ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_status_check;
+				ALTER TABLE requests ADD CONSTRAINT requests_status_check
+					CHECK (status IN ('active', 'completed', 'cancelled'));
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
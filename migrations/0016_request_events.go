@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_events (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					type TEXT NOT NULL,
+					field TEXT,
+					old_value TEXT,
+					new_value TEXT,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_events_request
+				ON request_events (request_id, created_at)
+			`); err != nil {
+				return err
+			}
+
+			// Give existing requests a creation entry so every timeline
+			// starts at the beginning.
+			if _, err := db.ExecContext(ctx, `
+				INSERT INTO request_events (request_id, actor_id, type, field, new_value, created_at)
+				SELECT r.id, r.user_id, 'created', 'status', 'active', r.created_at
+				FROM requests r
+				WHERE NOT EXISTS (SELECT 1 FROM request_events e WHERE e.request_id = r.id)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS request_events`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
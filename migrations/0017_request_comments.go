@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_comments (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					body TEXT NOT NULL,
+					internal BOOLEAN NOT NULL DEFAULT false,
+					created_at TIMESTAMP DEFAULT now(),
+					edited_at TIMESTAMP,
+					deleted_at TIMESTAMP
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_comments_request
+				ON request_comments (request_id, created_at)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS request_comments`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_attachments (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					filename TEXT NOT NULL,
+					content_type TEXT NOT NULL,
+					size_bytes BIGINT NOT NULL,
+					storage_key TEXT UNIQUE NOT NULL,
+					thumbnail_key TEXT,
+					created_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_attachments_request
+				ON request_attachments (request_id, created_at)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS request_attachments`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				ADD COLUMN IF NOT EXISTS suggested_priority TEXT NOT NULL DEFAULT 'normal'
+					CHECK (suggested_priority IN ('low', 'normal', 'high', 'emergency')),
+				ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'normal'
+					CHECK (priority IN ('low', 'normal', 'high', 'emergency')),
+				ADD COLUMN IF NOT EXISTS priority_confirmed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+				ADD COLUMN IF NOT EXISTS priority_confirmed_at TIMESTAMP
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_requests_open_emergencies
+				ON requests (created_at)
+				WHERE priority = 'emergency' AND status NOT IN ('completed', 'cancelled')
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS on_call BOOLEAN NOT NULL DEFAULT false
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `ALTER TABLE users DROP COLUMN IF EXISTS on_call`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP INDEX IF EXISTS idx_requests_open_emergencies`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				DROP COLUMN IF EXISTS priority_confirmed_at,
+				DROP COLUMN IF EXISTS priority_confirmed_by,
+				DROP COLUMN IF EXISTS priority,
+				DROP COLUMN IF EXISTS suggested_priority
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
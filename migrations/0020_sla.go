@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS sla_policies (
+					id SERIAL PRIMARY KEY,
+					type TEXT NOT NULL,
+					priority TEXT NOT NULL CHECK (priority IN ('low', 'normal', 'high', 'emergency')),
+					resolve_within_minutes INT NOT NULL CHECK (resolve_within_minutes > 0),
+					warn_before_minutes INT NOT NULL DEFAULT 0 CHECK (warn_before_minutes >= 0),
+					updated_at TIMESTAMP DEFAULT now(),
+					CONSTRAINT sla_policies_type_priority_key UNIQUE (type, priority)
+				)
+			`); err != nil {
+				return err
+			}
+
+			// Cleaning within a day and maintenance within three days, with
+			// emergencies of either kind within four hours.
+			if _, err := db.ExecContext(ctx, `
+				INSERT INTO sla_policies (type, priority, resolve_within_minutes, warn_before_minutes)
+				VALUES
+					('cleaning', 'low', 1440, 240),
+					('cleaning', 'normal', 1440, 240),
+					('cleaning', 'high', 1440, 240),
+					('cleaning', 'emergency', 240, 60),
+					('maintenance', 'low', 4320, 720),
+					('maintenance', 'normal', 4320, 720),
+					('maintenance', 'high', 4320, 720),
+					('maintenance', 'emergency', 240, 60)
+				ON CONFLICT (type, priority) DO NOTHING
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				ADD COLUMN IF NOT EXISTS sla_due_at TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS sla_at_risk_at TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS sla_breached_at TIMESTAMP
+			`); err != nil {
+				return err
+			}
+
+			// Open requests get a deadline measured from when they were filed.
+			if _, err := db.ExecContext(ctx, `
+				UPDATE requests r
+				SET sla_due_at = r.created_at + p.resolve_within_minutes * interval '1 minute'
+				FROM sla_policies p
+				WHERE p.type = r.type
+				AND p.priority = r.priority
+				AND r.sla_due_at IS NULL
+				AND r.status NOT IN ('completed', 'cancelled')
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_requests_sla_due
+				ON requests (sla_due_at)
+				WHERE sla_breached_at IS NULL AND status NOT IN ('completed', 'cancelled')
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS sla_breaches (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					due_at TIMESTAMP NOT NULL,
+					breached_at TIMESTAMP DEFAULT now(),
+					escalated_at TIMESTAMP,
+					CONSTRAINT sla_breaches_request_due_key UNIQUE (request_id, due_at)
+				)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS sla_breaches`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP INDEX IF EXISTS idx_requests_sla_due`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				DROP COLUMN IF EXISTS sla_breached_at,
+				DROP COLUMN IF EXISTS sla_at_risk_at,
+				DROP COLUMN IF EXISTS sla_due_at
+			`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS sla_policies`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
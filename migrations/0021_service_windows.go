@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS block_service_hours (
+					id SERIAL PRIMARY KEY,
+					block TEXT NOT NULL,
+					weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+					opens_at TIME NOT NULL,
+					closes_at TIME NOT NULL,
+					created_at TIMESTAMP DEFAULT now(),
+					CONSTRAINT block_service_hours_span_check CHECK (opens_at < closes_at)
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_block_service_hours_block
+				ON block_service_hours (lower(block), weekday)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_time_windows (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					starts_at TIMESTAMP NOT NULL,
+					ends_at TIMESTAMP NOT NULL,
+					CONSTRAINT request_time_windows_span_check CHECK (starts_at < ends_at)
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_time_windows_request
+				ON request_time_windows (request_id, starts_at)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_access_attempts (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					note TEXT,
+					attempted_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_access_attempts_request
+				ON request_access_attempts (request_id, attempted_at)
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			for _, table := range []string{"request_access_attempts", "request_time_windows", "block_service_hours"} {
+				if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS `+table); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
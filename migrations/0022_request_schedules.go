@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_schedules (
+					id SERIAL PRIMARY KEY,
+					room_id INT NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+					type TEXT NOT NULL,
+					priority TEXT NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'emergency')),
+					description TEXT,
+					recurrence TEXT NOT NULL,
+					starts_on DATE NOT NULL,
+					window_start TIME NOT NULL,
+					window_end TIME NOT NULL,
+					active BOOLEAN NOT NULL DEFAULT true,
+					next_run_at TIMESTAMP,
+					last_run_at TIMESTAMP,
+					created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMP DEFAULT now(),
+					updated_at TIMESTAMP DEFAULT now(),
+					CONSTRAINT request_schedules_window_check CHECK (window_start < window_end)
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_schedules_due
+				ON request_schedules (next_run_at)
+				WHERE active
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				DROP TRIGGER IF EXISTS request_schedules_set_updated_at ON request_schedules;
+				CREATE TRIGGER request_schedules_set_updated_at
+				BEFORE UPDATE ON request_schedules
+				FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				ADD COLUMN IF NOT EXISTS schedule_id INT REFERENCES request_schedules(id) ON DELETE SET NULL
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `ALTER TABLE requests DROP COLUMN IF EXISTS schedule_id`); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS request_schedules`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_types (
+					name TEXT PRIMARY KEY CHECK (name ~ '^[a-z][a-z0-9_]*$'),
+					label TEXT NOT NULL,
+					category TEXT NOT NULL CHECK (category IN ('cleaning', 'maintenance')),
+					default_priority TEXT NOT NULL DEFAULT 'normal' CHECK (default_priority IN ('low', 'normal', 'high', 'emergency')),
+					sla_resolve_within_minutes INT CHECK (sla_resolve_within_minutes > 0),
+					sla_warn_before_minutes INT NOT NULL DEFAULT 0 CHECK (sla_warn_before_minutes >= 0),
+					one_active_per_room BOOLEAN NOT NULL DEFAULT true,
+					active BOOLEAN NOT NULL DEFAULT true,
+					created_at TIMESTAMP DEFAULT now(),
+					updated_at TIMESTAMP DEFAULT now()
+				)
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				DROP TRIGGER IF EXISTS request_types_set_updated_at ON request_types;
+				CREATE TRIGGER request_types_set_updated_at
+				BEFORE UPDATE ON request_types
+				FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+			`); err != nil {
+				return err
+			}
+
+			// The two original types keep their SLAs as defaults; the trades
+			// split out of general maintenance are handled by technicians and
+			// pest control by the cleaning staff.
+			if _, err := db.ExecContext(ctx, `
+				INSERT INTO request_types (name, label, category, sla_resolve_within_minutes, sla_warn_before_minutes)
+				VALUES
+					('cleaning', 'Cleaning', 'cleaning', 1440, 240),
+					('maintenance', 'General maintenance', 'maintenance', 4320, 720),
+					('electrical', 'Electrical', 'maintenance', 1440, 240),
+					('plumbing', 'Plumbing', 'maintenance', 1440, 240),
+					('carpentry', 'Carpentry', 'maintenance', 4320, 720),
+					('pest_control', 'Pest control', 'cleaning', 4320, 720),
+					('internet', 'Internet', 'maintenance', 1440, 240),
+					('furniture', 'Furniture', 'maintenance', 4320, 720)
+				ON CONFLICT (name) DO NOTHING
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				INSERT INTO sla_policies (type, priority, resolve_within_minutes, warn_before_minutes)
+				SELECT name, 'emergency', 240, 60
+				FROM request_types
+				ON CONFLICT (type, priority) DO NOTHING
+			`); err != nil {
+				return err
+			}
+
+			// Requests remember whether their type allowed only one open
+			// request per room when they were filed, so the unique index can
+			// apply to just those.
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests
+				DROP CONSTRAINT IF EXISTS requests_type_check,
+				ADD COLUMN IF NOT EXISTS one_active BOOLEAN NOT NULL DEFAULT true
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				DROP INDEX IF EXISTS unique_open_request_per_room_type;
+				CREATE UNIQUE INDEX unique_open_request_per_room_type
+				ON requests (room_id, type)
+				WHERE one_active AND status NOT IN ('completed', 'cancelled');
+			`); err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_type_fkey;
+				ALTER TABLE requests ADD CONSTRAINT requests_type_fkey
+					FOREIGN KEY (type) REFERENCES request_types(name);
+				ALTER TABLE request_schedules DROP CONSTRAINT IF EXISTS request_schedules_type_fkey;
+				ALTER TABLE request_schedules ADD CONSTRAINT request_schedules_type_fkey
+					FOREIGN KEY (type) REFERENCES request_types(name);
+				ALTER TABLE sla_policies DROP CONSTRAINT IF EXISTS sla_policies_type_fkey;
+				ALTER TABLE sla_policies ADD CONSTRAINT sla_policies_type_fkey
+					FOREIGN KEY (type) REFERENCES request_types(name) ON DELETE CASCADE;
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				ALTER TABLE sla_policies DROP CONSTRAINT IF EXISTS sla_policies_type_fkey;
+				ALTER TABLE request_schedules DROP CONSTRAINT IF EXISTS request_schedules_type_fkey;
+				ALTER TABLE requests DROP CONSTRAINT IF EXISTS requests_type_fkey;
+				DROP INDEX IF EXISTS unique_open_request_per_room_type;
+				CREATE UNIQUE INDEX IF NOT EXISTS unique_open_request_per_room_type
+				ON requests (room_id, type)
+				WHERE status NOT IN ('completed', 'cancelled');
+				ALTER TABLE requests DROP COLUMN IF EXISTS one_active;
+				DELETE FROM sla_policies WHERE type NOT IN ('cleaning', 'maintenance');
+				DELETE FROM request_schedules WHERE type NOT IN ('cleaning', 'maintenance');
+				ALTER TABLE requests ADD CONSTRAINT requests_type_check
+					CHECK (type IN ('cleaning', 'maintenance')) NOT VALID;
+				DROP TABLE IF EXISTS request_types;
+			`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
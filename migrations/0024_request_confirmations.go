@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS request_confirmations (
+					id BIGSERIAL PRIMARY KEY,
+					request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+					status TEXT NOT NULL DEFAULT 'pending'
+						CHECK (status IN ('pending', 'confirmed', 'disputed', 'auto_accepted')),
+					completed_at TIMESTAMP DEFAULT now(),
+					auto_accept_at TIMESTAMP NOT NULL,
+					dispute_until TIMESTAMP NOT NULL,
+					responded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+					responded_at TIMESTAMP,
+					rating SMALLINT CHECK (rating BETWEEN 1 AND 5),
+					feedback TEXT
+				)
+			`); err != nil {
+				return err
+			}
+
+			// A request has at most one confirmation waiting for an answer,
+			// and the auto-accept worker scans the pending ones by deadline.
+			if _, err := db.ExecContext(ctx, `
+				CREATE INDEX IF NOT EXISTS idx_request_confirmations_request
+				ON request_confirmations (request_id, completed_at);
+				CREATE UNIQUE INDEX IF NOT EXISTS unique_pending_confirmation_per_request
+				ON request_confirmations (request_id)
+				WHERE status = 'pending';
+				CREATE INDEX IF NOT EXISTS idx_request_confirmations_auto_accept
+				ON request_confirmations (auto_accept_at)
+				WHERE status = 'pending';
+			`); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		func(ctx context.Context, db *bun.DB) error {
+			if _, err := db.ExecContext(ctx, `DROP TABLE IF EXISTS request_confirmations`); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
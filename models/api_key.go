@@ -0,0 +1,51 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type APIKeyKind string
+
+const (
+	APIKeyKindPersonal APIKeyKind = "personal"
+	APIKeyKindService  APIKeyKind = "service"
+)
+
+// Scopes an API key can be granted. Session-authenticated users implicitly
+// hold every scope.
+const (
+	ScopeRequestsRead  = "requests:read"
+	ScopeRequestsWrite = "requests:write"
+)
+
+// APIScopes lists every scope that can be granted to an API key.
+var APIScopes = []string{ScopeRequestsRead, ScopeRequestsWrite}
+
+type APIKey struct {
+	bun.BaseModel `bun:"table:api_keys,alias:ak"`
+
+	ID         int        `bun:"id,pk,autoincrement" json:"id"`
+	UserID     uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	Name       string     `bun:"name,notnull" json:"name"`
+	Kind       APIKeyKind `bun:"kind,notnull,default:'personal'" json:"kind"`
+	Prefix     string     `bun:"prefix,notnull,unique" json:"prefix"`
+	KeyHash    string     `bun:"key_hash,notnull,unique" json:"-"`
+	Scopes     []string   `bun:"scopes,array,notnull" json:"scopes"`
+	ExpiresAt  *time.Time `bun:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `bun:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `bun:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedBy  *uuid.UUID `bun:"created_by,type:uuid" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type EmailVerificationToken struct {
+	bun.BaseModel `bun:"table:email_verification_tokens,alias:evt"`
+
+	ID        int        `bun:"id,pk,autoincrement" json:"id"`
+	UserID    uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	TokenHash string     `bun:"token_hash,notnull,unique" json:"-"`
+	ExpiresAt time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	UsedAt    *time.Time `bun:"used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
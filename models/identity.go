@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Identity links a user to an account at an external OpenID Connect provider.
+type Identity struct {
+	bun.BaseModel `bun:"table:identities,alias:idn"`
+
+	ID          int        `bun:"id,pk,autoincrement" json:"id"`
+	UserID      uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	Issuer      string     `bun:"issuer,notnull,unique:identity_issuer_subject" json:"issuer"`
+	Subject     string     `bun:"subject,notnull,unique:identity_issuer_subject" json:"subject"`
+	Email       *string    `bun:"email" json:"email,omitempty"`
+	CreatedAt   time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	LastLoginAt *time.Time `bun:"last_login_at" json:"last_login_at,omitempty"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
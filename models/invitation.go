@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Invitation lets a warden or administrator onboard a user into a specific
+// room. Redeeming it creates the account and its room membership.
+type Invitation struct {
+	bun.BaseModel `bun:"table:invitations,alias:inv"`
+
+	ID             int        `bun:"id,pk,autoincrement" json:"id"`
+	Email          string     `bun:"email,notnull" json:"email"`
+	RoomID         int        `bun:"room_id,notnull" json:"room_id"`
+	Block          string     `bun:"block,notnull" json:"block"`
+	Role           Role       `bun:"role,notnull,default:'resident'" json:"role"`
+	TokenHash      string     `bun:"token_hash,notnull,unique" json:"-"`
+	ExpiresAt      time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	InvitedBy      *uuid.UUID `bun:"invited_by,type:uuid" json:"invited_by,omitempty"`
+	AcceptedAt     *time.Time `bun:"accepted_at" json:"accepted_at,omitempty"`
+	AcceptedUserID *uuid.UUID `bun:"accepted_user_id,type:uuid" json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `bun:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	Room *Room `bun:"rel:belongs-to,join:room_id=id,join:block=block" json:"room,omitempty"`
+}
+
+// Pending reports whether the invitation can still be redeemed.
+func (i *Invitation) Pending() bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type LoginAttempt struct {
+	bun.BaseModel `bun:"table:login_attempts,alias:la"`
+
+	ID        int64      `bun:"id,pk,autoincrement" json:"id"`
+	Email     string     `bun:"email,notnull" json:"email"`
+	UserID    *uuid.UUID `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	IP        string     `bun:"ip,notnull" json:"ip"`
+	Success   bool       `bun:"success,notnull" json:"success"`
+	CreatedAt time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type RecoveryCode struct {
+	bun.BaseModel `bun:"table:two_factor_recovery_codes,alias:rc"`
+
+	ID        int        `bun:"id,pk,autoincrement" json:"id"`
+	UserID    uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	CodeHash  string     `bun:"code_hash,notnull" json:"-"`
+	UsedAt    *time.Time `bun:"used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:rt"`
+
+	ID         uuid.UUID  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	SessionID  *uuid.UUID `bun:"session_id,type:uuid" json:"session_id,omitempty"`
+	TokenHash  string     `bun:"token_hash,notnull,unique" json:"-"`
+	ExpiresAt  time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	RevokedAt  *time.Time `bun:"revoked_at" json:"revoked_at,omitempty"`
+	ReplacedBy *uuid.UUID `bun:"replaced_by,type:uuid" json:"replaced_by,omitempty"`
+	CreatedAt  time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
@@ -1,37 +1,111 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 )
 
+// RequestType names a row in request_types. Cleaning and maintenance are
+// built in; admins can add more.
 type RequestType string
 type RequestStatus string
+type RequestPriority string
 
 const (
 	RequestTypeCleaning    RequestType = "cleaning"
 	RequestTypeMaintenance RequestType = "maintenance"
 
-	RequestStatusActive    RequestStatus = "active"
-	RequestStatusCompleted RequestStatus = "completed"
-	RequestStatusCancelled RequestStatus = "cancelled"
+	// RequestStatusActive is a newly filed request nobody has picked up yet.
+	RequestStatusActive        RequestStatus = "active"
+	RequestStatusAssigned      RequestStatus = "assigned"
+	RequestStatusInProgress    RequestStatus = "in_progress"
+	RequestStatusOnHold        RequestStatus = "on_hold"
+	RequestStatusAwaitingParts RequestStatus = "awaiting_parts"
+	RequestStatusCompleted     RequestStatus = "completed"
+	RequestStatusCancelled     RequestStatus = "cancelled"
 )
 
+const (
+	RequestPriorityLow       RequestPriority = "low"
+	RequestPriorityNormal    RequestPriority = "normal"
+	RequestPriorityHigh      RequestPriority = "high"
+	RequestPriorityEmergency RequestPriority = "emergency"
+)
+
+// Valid reports whether p is one of the known priorities.
+func (p RequestPriority) Valid() bool {
+	switch p {
+	case RequestPriorityLow, RequestPriorityNormal, RequestPriorityHigh, RequestPriorityEmergency:
+		return true
+	}
+	return false
+}
+
+// OpenRequestStatuses are the non-terminal statuses. A room has at most one
+// open request per type unless the type allows several.
+var OpenRequestStatuses = []RequestStatus{
+	RequestStatusActive,
+	RequestStatusAssigned,
+	RequestStatusInProgress,
+	RequestStatusOnHold,
+	RequestStatusAwaitingParts,
+}
+
+// IsOpen reports whether s is a non-terminal status.
+func (s RequestStatus) IsOpen() bool {
+	return slices.Contains(OpenRequestStatuses, s)
+}
+
+// Valid reports whether s is one of the known statuses.
+func (s RequestStatus) Valid() bool {
+	return s.IsOpen() || s == RequestStatusCompleted || s == RequestStatusCancelled
+}
+
 type Request struct {
 	bun.BaseModel `bun:"table:requests,alias:req"`
 
-	ID          int            `bun:"id,pk,autoincrement" json:"id"`
-	UserID      *uuid.UUID     `bun:"user_id,type:uuid" json:"user_id,omitempty"`
-	RoomID      int            `bun:"room_id,notnull" json:"room_id"`
-	Type        RequestType    `bun:"type,notnull" json:"type"`
-	Status      RequestStatus  `bun:"status,default:'active'" json:"status"`
-	Description *string        `bun:"description" json:"description,omitempty"`
-	CreatedAt   time.Time      `bun:"created_at,nullzero,default:now()" json:"created_at"`
-	UpdatedAt   time.Time      `bun:"updated_at,nullzero,default:now()" json:"updated_at"`
+	ID          int           `bun:"id,pk,autoincrement" json:"id"`
+	UserID      *uuid.UUID    `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	RoomID      int           `bun:"room_id,notnull" json:"room_id"`
+	Type        RequestType   `bun:"type,notnull" json:"type"`
+	Status      RequestStatus `bun:"status,default:'active'" json:"status"`
+	Description *string       `bun:"description" json:"description,omitempty"`
+	CreatedAt   time.Time     `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	UpdatedAt   time.Time     `bun:"updated_at,nullzero,default:now()" json:"updated_at"`
+	AssigneeID  *uuid.UUID    `bun:"assignee_id,type:uuid" json:"assignee_id,omitempty"`
+	AssignedAt  *time.Time    `bun:"assigned_at" json:"assigned_at,omitempty"`
+
+	// SuggestedPriority is what the reporter asked for. Priority is the one
+	// in effect: the suggestion until staff confirm or change it.
+	SuggestedPriority   RequestPriority `bun:"suggested_priority,notnull,default:'normal'" json:"suggested_priority"`
+	Priority            RequestPriority `bun:"priority,notnull,default:'normal'" json:"priority"`
+	PriorityConfirmedBy *uuid.UUID      `bun:"priority_confirmed_by,type:uuid" json:"priority_confirmed_by,omitempty"`
+	PriorityConfirmedAt *time.Time      `bun:"priority_confirmed_at" json:"priority_confirmed_at,omitempty"`
+
+	// SLADueAt is when the request must be resolved by under its SLA policy;
+	// nil when no policy covers it. The checker sets SLAAtRiskAt and
+	// SLABreachedAt as the deadline approaches and passes.
+	SLADueAt      *time.Time `bun:"sla_due_at" json:"sla_due_at,omitempty"`
+	SLAAtRiskAt   *time.Time `bun:"sla_at_risk_at" json:"sla_at_risk_at,omitempty"`
+	SLABreachedAt *time.Time `bun:"sla_breached_at" json:"sla_breached_at,omitempty"`
+
+	// ScheduleID is set on requests filed by a recurring schedule.
+	ScheduleID *int `bun:"schedule_id" json:"schedule_id,omitempty"`
+
+	// OneActive copies the type's one-active-per-room flag when the request
+	// is filed; the unique index on open requests only covers these.
+	OneActive bool `bun:"one_active,notnull" json:"-"`
 
 	// Relations
-	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
-	Room *Room `bun:"rel:belongs-to,join:room_id=id" json:"room,omitempty"`
+	User     *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+	Room     *Room `bun:"rel:belongs-to,join:room_id=id" json:"room,omitempty"`
+	Assignee *User `bun:"rel:belongs-to,join:assignee_id=id" json:"assignee,omitempty"`
+
+	TypeConfig *RequestTypeConfig `bun:"rel:belongs-to,join:type=name" json:"type_config,omitempty"`
+
+	TimeWindows      []*RequestTimeWindow    `bun:"rel:has-many,join:id=request_id" json:"time_windows,omitempty"`
+	NoAccessAttempts []*RequestAccessAttempt `bun:"rel:has-many,join:id=request_id" json:"no_access_attempts,omitempty"`
 }
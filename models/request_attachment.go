@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RequestAttachment is a file uploaded to a request. The bytes live in the
+// blob store under StorageKey; images also get a JPEG thumbnail under
+// ThumbnailKey.
+type RequestAttachment struct {
+	bun.BaseModel `bun:"table:request_attachments,alias:att"`
+
+	ID           int64      `bun:"id,pk,autoincrement" json:"id"`
+	RequestID    int        `bun:"request_id,notnull" json:"request_id"`
+	UploaderID   *uuid.UUID `bun:"uploader_id,type:uuid" json:"uploader_id,omitempty"`
+	Filename     string     `bun:"filename,notnull" json:"filename"`
+	ContentType  string     `bun:"content_type,notnull" json:"content_type"`
+	SizeBytes    int64      `bun:"size_bytes,notnull" json:"size_bytes"`
+	StorageKey   string     `bun:"storage_key,notnull" json:"-"`
+	ThumbnailKey *string    `bun:"thumbnail_key" json:"-"`
+	CreatedAt    time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Signed download links, filled in per response.
+	URL          string     `bun:"-" json:"url,omitempty"`
+	ThumbnailURL string     `bun:"-" json:"thumbnail_url,omitempty"`
+	URLExpiresAt *time.Time `bun:"-" json:"url_expires_at,omitempty"`
+
+	// Relations
+	Uploader *User `bun:"rel:belongs-to,join:uploader_id=id" json:"uploader,omitempty"`
+}
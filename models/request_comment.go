@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RequestComment is a message on a request's thread. Internal comments are
+// staff notes hidden from residents. Deleted comments keep their row with
+// DeletedAt set.
+type RequestComment struct {
+	bun.BaseModel `bun:"table:request_comments,alias:com"`
+
+	ID        int64      `bun:"id,pk,autoincrement" json:"id"`
+	RequestID int        `bun:"request_id,notnull" json:"request_id"`
+	AuthorID  *uuid.UUID `bun:"author_id,type:uuid" json:"author_id,omitempty"`
+	Body      string     `bun:"body,notnull" json:"body"`
+	Internal  bool       `bun:"internal,notnull,default:false" json:"internal"`
+	CreatedAt time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	EditedAt  *time.Time `bun:"edited_at" json:"edited_at,omitempty"`
+	DeletedAt *time.Time `bun:"deleted_at" json:"-"`
+
+	// Relations
+	Author *User `bun:"rel:belongs-to,join:author_id=id" json:"author,omitempty"`
+}
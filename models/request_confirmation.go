@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type ConfirmationStatus string
+
+const (
+	// ConfirmationPending is waiting for a room member to answer.
+	ConfirmationPending      ConfirmationStatus = "pending"
+	ConfirmationConfirmed    ConfirmationStatus = "confirmed"
+	ConfirmationDisputed     ConfirmationStatus = "disputed"
+	ConfirmationAutoAccepted ConfirmationStatus = "auto_accepted"
+)
+
+// RequestConfirmation asks the members of a room to confirm that a completed
+// request was done, with a 1-5 rating and feedback. Unanswered confirmations
+// are accepted automatically at AutoAcceptAt. Until DisputeUntil a member
+// may dispute the work instead, which reopens the request. A request gets a
+// new confirmation each time it is completed.
+type RequestConfirmation struct {
+	bun.BaseModel `bun:"table:request_confirmations,alias:rcf"`
+
+	ID           int64              `bun:"id,pk,autoincrement" json:"id"`
+	RequestID    int                `bun:"request_id,notnull" json:"request_id"`
+	Status       ConfirmationStatus `bun:"status,notnull" json:"status"`
+	CompletedAt  time.Time          `bun:"completed_at,nullzero,default:now()" json:"completed_at"`
+	AutoAcceptAt time.Time          `bun:"auto_accept_at,notnull" json:"auto_accept_at"`
+	DisputeUntil time.Time          `bun:"dispute_until,notnull" json:"dispute_until"`
+	RespondedBy  *uuid.UUID         `bun:"responded_by,type:uuid" json:"responded_by,omitempty"`
+	RespondedAt  *time.Time         `bun:"responded_at" json:"responded_at,omitempty"`
+	Rating       *int               `bun:"rating" json:"rating,omitempty"`
+	Feedback     *string            `bun:"feedback" json:"feedback,omitempty"`
+
+	// Relations
+	Request   *Request `bun:"rel:belongs-to,join:request_id=id" json:"request,omitempty"`
+	Responder *User    `bun:"rel:belongs-to,join:responded_by=id" json:"responder,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type RequestEventType string
+
+const (
+	RequestEventCreated       RequestEventType = "created"
+	RequestEventStatusChanged RequestEventType = "status_changed"
+	RequestEventAssigned      RequestEventType = "assigned"
+	RequestEventEdited        RequestEventType = "edited"
+	RequestEventPriority      RequestEventType = "priority_changed"
+	RequestEventSLAAtRisk     RequestEventType = "sla_at_risk"
+	RequestEventSLABreached   RequestEventType = "sla_breached"
+	RequestEventNoAccess      RequestEventType = "no_access"
+	RequestEventConfirmed     RequestEventType = "confirmed"
+	RequestEventDisputed      RequestEventType = "disputed"
+)
+
+// RequestEvent is one entry in a request's history. ActorID is nil for
+// changes made by the system rather than a user.
+type RequestEvent struct {
+	bun.BaseModel `bun:"table:request_events,alias:rev"`
+
+	ID        int64            `bun:"id,pk,autoincrement" json:"id"`
+	RequestID int              `bun:"request_id,notnull" json:"request_id"`
+	ActorID   *uuid.UUID       `bun:"actor_id,type:uuid" json:"actor_id,omitempty"`
+	Type      RequestEventType `bun:"type,notnull" json:"type"`
+	Field     *string          `bun:"field" json:"field,omitempty"`
+	OldValue  *string          `bun:"old_value" json:"old_value,omitempty"`
+	NewValue  *string          `bun:"new_value" json:"new_value,omitempty"`
+	CreatedAt time.Time        `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	Actor *User `bun:"rel:belongs-to,join:actor_id=id" json:"actor,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RequestSchedule files a request for a room on every occurrence of an
+// RRULE-style recurrence, with a preferred visit window on each occurrence
+// day. WindowStart and WindowEnd are wall-clock times in the service time
+// zone. NextRunAt is the start of the next occurrence's window; nil once the
+// rule has ended.
+type RequestSchedule struct {
+	bun.BaseModel `bun:"table:request_schedules,alias:rs"`
+
+	ID          int             `bun:"id,pk,autoincrement" json:"id"`
+	RoomID      int             `bun:"room_id,notnull" json:"room_id"`
+	Type        RequestType     `bun:"type,notnull" json:"type"`
+	Priority    RequestPriority `bun:"priority,notnull,default:'normal'" json:"priority"`
+	Description *string         `bun:"description" json:"description,omitempty"`
+	Recurrence  string          `bun:"recurrence,notnull" json:"recurrence"`
+	StartsOn    time.Time       `bun:"starts_on,type:date,notnull" json:"starts_on"`
+	WindowStart string          `bun:"window_start,type:time,notnull" json:"window_start"`
+	WindowEnd   string          `bun:"window_end,type:time,notnull" json:"window_end"`
+	Active      bool            `bun:"active,notnull,default:true" json:"active"`
+	NextRunAt   *time.Time      `bun:"next_run_at" json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time      `bun:"last_run_at" json:"last_run_at,omitempty"`
+	CreatedBy   *uuid.UUID      `bun:"created_by,type:uuid" json:"created_by,omitempty"`
+	CreatedAt   time.Time       `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	UpdatedAt   time.Time       `bun:"updated_at,nullzero,default:now()" json:"updated_at"`
+
+	// Relations
+	Room    *Room `bun:"rel:belongs-to,join:room_id=id" json:"room,omitempty"`
+	Creator *User `bun:"rel:belongs-to,join:created_by=id" json:"creator,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// RequestCategory groups request types by the staff who handle them.
+type RequestCategory string
+
+const (
+	RequestCategoryCleaning    RequestCategory = "cleaning"
+	RequestCategoryMaintenance RequestCategory = "maintenance"
+)
+
+// Valid reports whether c is one of the known categories.
+func (c RequestCategory) Valid() bool {
+	return c == RequestCategoryCleaning || c == RequestCategoryMaintenance
+}
+
+// StaffRole is the role that works requests in category c: cleaners for
+// cleaning and technicians for maintenance.
+func (c RequestCategory) StaffRole() Role {
+	if c == RequestCategoryCleaning {
+		return RoleCleaner
+	}
+	return RoleTechnician
+}
+
+// RequestTypeConfig is a kind of request residents can file. The SLA fields
+// are the default target for priorities without their own SLA policy; a nil
+// SLAResolveWithinMinutes means those priorities are not tracked. When
+// OneActivePerRoom is set a room can have only one open request of the type.
+// Inactive types cannot be filed but existing requests keep them.
+type RequestTypeConfig struct {
+	bun.BaseModel `bun:"table:request_types,alias:rtype"`
+
+	Name                    RequestType     `bun:"name,pk" json:"name"`
+	Label                   string          `bun:"label,notnull" json:"label"`
+	Category                RequestCategory `bun:"category,notnull" json:"category"`
+	DefaultPriority         RequestPriority `bun:"default_priority,notnull" json:"default_priority"`
+	SLAResolveWithinMinutes *int            `bun:"sla_resolve_within_minutes" json:"sla_resolve_within_minutes,omitempty"`
+	SLAWarnBeforeMinutes    int             `bun:"sla_warn_before_minutes,notnull" json:"sla_warn_before_minutes"`
+	OneActivePerRoom        bool            `bun:"one_active_per_room,notnull" json:"one_active_per_room"`
+	Active                  bool            `bun:"active,notnull" json:"active"`
+	CreatedAt               time.Time       `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	UpdatedAt               time.Time       `bun:"updated_at,nullzero,default:now()" json:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RequestTimeWindow is a span during which the reporter would like staff to
+// visit.
+type RequestTimeWindow struct {
+	bun.BaseModel `bun:"table:request_time_windows,alias:rtw"`
+
+	ID        int64     `bun:"id,pk,autoincrement" json:"id"`
+	RequestID int       `bun:"request_id,notnull" json:"-"`
+	StartsAt  time.Time `bun:"starts_at,notnull" json:"starts_at"`
+	EndsAt    time.Time `bun:"ends_at,notnull" json:"ends_at"`
+}
+
+// RequestAccessAttempt records staff visiting a room and being unable to get
+// in.
+type RequestAccessAttempt struct {
+	bun.BaseModel `bun:"table:request_access_attempts,alias:raa"`
+
+	ID          int64      `bun:"id,pk,autoincrement" json:"id"`
+	RequestID   int        `bun:"request_id,notnull" json:"request_id"`
+	UserID      *uuid.UUID `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	Note        *string    `bun:"note" json:"note,omitempty"`
+	AttemptedAt time.Time  `bun:"attempted_at,nullzero,default:now()" json:"attempted_at"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
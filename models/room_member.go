@@ -14,6 +14,9 @@ type RoomMember struct {
 	Block    string    `bun:"block,pk,notnull" json:"block"`
 	UserID   uuid.UUID `bun:"user_id,pk,type:uuid" json:"user_id"`
 	JoinedAt time.Time `bun:"joined_at,nullzero,default:now()" json:"joined_at"`
+	// Active is false while the user is deactivated; the row is kept so the
+	// membership comes back on reactivation.
+	Active bool `bun:"active,notnull,default:true" json:"active"`
 
 	// Relations
 	Room *Room `bun:"rel:belongs-to,join:room_id=id,join:block=block" json:"room,omitempty"`
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type SecurityEventType string
+
+const (
+	SecurityEventAccountLocked   SecurityEventType = "account_locked"
+	SecurityEventAccountUnlocked SecurityEventType = "account_unlocked"
+	SecurityEventIPThrottled     SecurityEventType = "ip_throttled"
+	SecurityEventUserDeactivated SecurityEventType = "user_deactivated"
+	SecurityEventUserReactivated SecurityEventType = "user_reactivated"
+	SecurityEventRoleChanged     SecurityEventType = "role_changed"
+	SecurityEventPasswordReset   SecurityEventType = "password_reset_forced"
+)
+
+type SecurityEvent struct {
+	bun.BaseModel `bun:"table:security_events,alias:se"`
+
+	ID        int64             `bun:"id,pk,autoincrement" json:"id"`
+	Type      SecurityEventType `bun:"type,notnull" json:"type"`
+	UserID    *uuid.UUID        `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	ActorID   *uuid.UUID        `bun:"actor_id,type:uuid" json:"actor_id,omitempty"`
+	IP        *string           `bun:"ip" json:"ip,omitempty"`
+	Details   *string           `bun:"details" json:"details,omitempty"`
+	CreatedAt time.Time         `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// BlockServiceHours is one span of a weekday during which staff service rooms
+// in a block. Weekday counts from Sunday (0) as in time.Weekday; OpensAt and
+// ClosesAt are wall-clock times such as "08:00:00" in the service time zone.
+type BlockServiceHours struct {
+	bun.BaseModel `bun:"table:block_service_hours,alias:bsh"`
+
+	ID        int       `bun:"id,pk,autoincrement" json:"id"`
+	Block     string    `bun:"block,notnull" json:"block"`
+	Weekday   int       `bun:"weekday,notnull" json:"weekday"`
+	OpensAt   string    `bun:"opens_at,type:time,notnull" json:"opens_at"`
+	ClosesAt  string    `bun:"closes_at,type:time,notnull" json:"closes_at"`
+	CreatedAt time.Time `bun:"created_at,nullzero,default:now()" json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Session struct {
+	bun.BaseModel `bun:"table:sessions,alias:s"`
+
+	ID         uuid.UUID  `bun:"id,pk,type:uuid,default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `bun:"user_id,notnull,type:uuid" json:"user_id"`
+	UserAgent  string     `bun:"user_agent,notnull,default:''" json:"user_agent"`
+	IP         string     `bun:"ip,notnull,default:''" json:"ip"`
+	CreatedAt  time.Time  `bun:"created_at,nullzero,default:now()" json:"created_at"`
+	LastSeenAt time.Time  `bun:"last_seen_at,nullzero,default:now()" json:"last_seen_at"`
+	ExpiresAt  time.Time  `bun:"expires_at,notnull" json:"expires_at"`
+	RevokedAt  *time.Time `bun:"revoked_at" json:"revoked_at,omitempty"`
+
+	// Current marks the session the listing request was made from.
+	Current bool `bun:"-" json:"current"`
+
+	// Relations
+	User *User `bun:"rel:belongs-to,join:user_id=id" json:"user,omitempty"`
+}
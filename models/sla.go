@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SLAState describes how a request is tracking against its SLA target.
+type SLAState string
+
+const (
+	SLAStateOK       SLAState = "ok"
+	SLAStateAtRisk   SLAState = "at_risk"
+	SLAStateBreached SLAState = "breached"
+)
+
+// SLAPolicy is the promised resolution time for requests of one type and
+// priority. Requests are flagged at risk WarnBeforeMinutes before the target.
+type SLAPolicy struct {
+	bun.BaseModel `bun:"table:sla_policies,alias:sla"`
+
+	ID                   int             `bun:"id,pk,autoincrement" json:"id"`
+	Type                 RequestType     `bun:"type,notnull" json:"type"`
+	Priority             RequestPriority `bun:"priority,notnull" json:"priority"`
+	ResolveWithinMinutes int             `bun:"resolve_within_minutes,notnull" json:"resolve_within_minutes"`
+	WarnBeforeMinutes    int             `bun:"warn_before_minutes,notnull,default:0" json:"warn_before_minutes"`
+	UpdatedAt            time.Time       `bun:"updated_at,nullzero,default:now()" json:"updated_at"`
+}
+
+// SLABreach records a request missing its SLA target and when the block's
+// wardens were told about it.
+type SLABreach struct {
+	bun.BaseModel `bun:"table:sla_breaches,alias:slab"`
+
+	ID          int64      `bun:"id,pk,autoincrement" json:"id"`
+	RequestID   int        `bun:"request_id,notnull" json:"request_id"`
+	DueAt       time.Time  `bun:"due_at,notnull" json:"due_at"`
+	BreachedAt  time.Time  `bun:"breached_at,nullzero,default:now()" json:"breached_at"`
+	EscalatedAt *time.Time `bun:"escalated_at" json:"escalated_at,omitempty"`
+
+	// Relations
+	Request *Request `bun:"rel:belongs-to,join:request_id=id" json:"request,omitempty"`
+}
@@ -7,6 +7,31 @@ import (
 	"github.com/uptrace/bun"
 )
 
+type Role string
+
+const (
+	RoleResident   Role = "resident"
+	RoleCleaner    Role = "cleaner"
+	RoleTechnician Role = "technician"
+	RoleWarden     Role = "warden"
+	RoleAdmin      Role = "admin"
+)
+
+// IsStaff reports whether the role works requests for a block rather than
+// filing them for a room.
+func (r Role) IsStaff() bool {
+	return r == RoleCleaner || r == RoleTechnician || r == RoleWarden
+}
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleResident, RoleCleaner, RoleTechnician, RoleWarden, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	bun.BaseModel `bun:"table:users,alias:u"`
 
@@ -17,5 +42,24 @@ type User struct {
 	Block     *string   `bun:"block" json:"block,omitempty"`
 	RoomName  *string   `bun:"room_name" json:"room_name,omitempty"`
 	Phone     *string   `bun:"phone" json:"phone,omitempty"`
+	Role      Role      `bun:"role,notnull,default:'resident'" json:"role"`
 	CreatedAt time.Time `bun:"created_at,nullzero,default:now()" json:"created_at"`
+
+	EmailVerifiedAt   *time.Time `bun:"email_verified_at" json:"email_verified_at,omitempty"`
+	PasswordChangedAt *time.Time `bun:"password_changed_at" json:"-"`
+	FailedLoginCount  int        `bun:"failed_login_count,notnull,default:0" json:"-"`
+	LastFailedLoginAt *time.Time `bun:"last_failed_login_at" json:"-"`
+	LockedUntil       *time.Time `bun:"locked_until" json:"locked_until,omitempty"`
+	TOTPSecret        *string    `bun:"totp_secret" json:"-"`
+	TOTPEnabledAt     *time.Time `bun:"totp_enabled_at" json:"two_factor_enabled_at,omitempty"`
+	TOTPLastStep      *int64     `bun:"totp_last_step" json:"-"`
+	DeactivatedAt     *time.Time `bun:"deactivated_at" json:"deactivated_at,omitempty"`
+
+	// OnCall technicians are paged about emergency requests in their block.
+	OnCall bool `bun:"on_call,notnull,default:false" json:"on_call"`
+}
+
+// Active reports whether the account has not been deactivated.
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
 }
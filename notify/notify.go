@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/adii2ma/dbms-backend/mailer"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+// Emergency alerts the wardens of the request's block and, for request types
+// in the maintenance category, its on-call technicians. When no technician
+// there is on call, every active technician in the block is alerted instead
+// so the request is never left with nobody to handle it.
+func Emergency(ctx context.Context, db bun.IDB, request *models.Request) error {
+	if err := loadRoom(ctx, db, request); err != nil {
+		return err
+	}
+	if err := loadTypeConfig(ctx, db, request); err != nil {
+		return err
+	}
+
+	recipients, err := blockUsers(ctx, db, request.Room.Block, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("u.role = ?", models.RoleWarden)
+	})
+	if err != nil {
+		return err
+	}
+
+	if request.TypeConfig.Category == models.RequestCategoryMaintenance {
+		technicians, err := blockUsers(ctx, db, request.Room.Block, func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("u.role = ?", models.RoleTechnician).Where("u.on_call")
+		})
+		if err != nil {
+			return err
+		}
+		if len(technicians) == 0 {
+			logging.FromContext(ctx).Warn("no technician on call; alerting the whole block", "block", request.Room.Block, "request_id", request.ID)
+			technicians, err = blockUsers(ctx, db, request.Room.Block, func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("u.role = ?", models.RoleTechnician)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		recipients = append(recipients, technicians...)
+	}
+
+	subject := fmt.Sprintf("EMERGENCY: %s request in block %s, room %s", request.Type, request.Room.Block, request.Room.RoomNumber)
+	body := fmt.Sprintf("An emergency %s request was raised for room %s in block %s.\n\n%s\n\nOpen it here: %s",
+		request.Type, request.Room.RoomNumber, request.Room.Block, description(request), requestLink(request))
+	return send(ctx, recipients, subject, body)
+}
+
+// SLABreach escalates a request that missed its SLA deadline to the wardens
+// of its block.
+func SLABreach(ctx context.Context, db bun.IDB, request *models.Request, breach *models.SLABreach) error {
+	if err := loadRoom(ctx, db, request); err != nil {
+		return err
+	}
+
+	wardens, err := blockUsers(ctx, db, request.Room.Block, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("u.role = ?", models.RoleWarden)
+	})
+	if err != nil {
+		return err
+	}
+	if len(wardens) == 0 {
+		logging.FromContext(ctx).Warn("no warden to escalate SLA breach to", "block", request.Room.Block, "request_id", request.ID)
+		return nil
+	}
+
+	subject := fmt.Sprintf("SLA breached: %s request in block %s, room %s", request.Type, request.Room.Block, request.Room.RoomNumber)
+	body := fmt.Sprintf("The %s priority %s request for room %s in block %s was due by %s and is still %s.\n\n%s\n\nOpen it here: %s",
+		request.Priority, request.Type, request.Room.RoomNumber, request.Room.Block,
+		breach.DueAt.UTC().Format("2 Jan 2006 15:04 MST"), strings.ReplaceAll(string(request.Status), "_", " "),
+		description(request), requestLink(request))
+	return send(ctx, wardens, subject, body)
+}
+
+// NoAccess tells the reporter that staff could not get into their room and
+// asks them to add preferred visit times.
+func NoAccess(ctx context.Context, db bun.IDB, request *models.Request, attempt *models.RequestAccessAttempt) error {
+	if request.UserID == nil {
+		return nil
+	}
+	if err := loadRoom(ctx, db, request); err != nil {
+		return err
+	}
+
+	reporter := new(models.User)
+	if err := db.NewSelect().Model(reporter).Where("id = ?", *request.UserID).Scan(ctx); err != nil {
+		return err
+	}
+	if !reporter.Active() {
+		return nil
+	}
+
+	note := ""
+	if attempt.Note != nil {
+		note = "\n\nNote from staff: " + *attempt.Note
+	}
+	subject := fmt.Sprintf("We couldn't get into room %s", request.Room.RoomNumber)
+	body := fmt.Sprintf("Staff visited room %s in block %s at %s for your %s request but could not get in.%s\n\nAdd the times you will be in so we can come back: %s",
+		request.Room.RoomNumber, request.Room.Block, attempt.AttemptedAt.UTC().Format("2 Jan 2006 15:04 MST"), request.Type, note, requestLink(request))
+	return send(ctx, []models.User{*reporter}, subject, body)
+}
+
+// CompletionConfirmation asks the active members of a completed request's
+// room to confirm the work and rate it, telling them when it will be accepted
+// on their behalf.
+func CompletionConfirmation(ctx context.Context, db bun.IDB, request *models.Request, confirmation *models.RequestConfirmation) error {
+	if err := loadRoom(ctx, db, request); err != nil {
+		return err
+	}
+
+	var members []models.User
+	if err := db.NewSelect().
+		Model(&members).
+		Where("u.id IN (SELECT user_id FROM room_members WHERE room_id = ? AND active)", request.RoomID).
+		Where("u.role = ?", models.RoleResident).
+		Where("u.deactivated_at IS NULL").
+		Scan(ctx); err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Was your %s request for room %s done?", request.Type, request.Room.RoomNumber)
+	body := fmt.Sprintf("Staff marked the %s request for room %s in block %s as completed.\n\n%s\n\nPlease confirm the work and rate it from 1 to 5, or dispute it if it was not done: %s\n\nIf nobody answers by %s it will be accepted automatically. It can be disputed until %s.",
+		request.Type, request.Room.RoomNumber, request.Room.Block, description(request), requestLink(request),
+		confirmation.AutoAcceptAt.UTC().Format("2 Jan 2006 15:04 MST"), confirmation.DisputeUntil.UTC().Format("2 Jan 2006 15:04 MST"))
+	return send(ctx, members, subject, body)
+}
+
+// Disputed tells the assignee and the wardens of the block that a resident
+// disputed a completed request and it has been reopened.
+func Disputed(ctx context.Context, db bun.IDB, request *models.Request, confirmation *models.RequestConfirmation) error {
+	if err := loadRoom(ctx, db, request); err != nil {
+		return err
+	}
+
+	recipients, err := blockUsers(ctx, db, request.Room.Block, func(q *bun.SelectQuery) *bun.SelectQuery {
+		if request.AssigneeID != nil {
+			return q.Where("(u.role = ? OR u.id = ?)", models.RoleWarden, *request.AssigneeID)
+		}
+		return q.Where("u.role = ?", models.RoleWarden)
+	})
+	if err != nil {
+		return err
+	}
+
+	feedback := ""
+	if confirmation.Feedback != nil {
+		feedback = "\n\nResident's feedback: " + *confirmation.Feedback
+	}
+	subject := fmt.Sprintf("Reopened: %s request in block %s, room %s", request.Type, request.Room.Block, request.Room.RoomNumber)
+	body := fmt.Sprintf("A resident disputed the completed %s request for room %s in block %s, so it has been reopened as %s.%s\n\nOpen it here: %s",
+		request.Type, request.Room.RoomNumber, request.Room.Block, strings.ReplaceAll(string(request.Status), "_", " "), feedback, requestLink(request))
+	return send(ctx, recipients, subject, body)
+}
+
+// blockUsers returns the active users of block matching filter.
+func blockUsers(ctx context.Context, db bun.IDB, block string, filter func(*bun.SelectQuery) *bun.SelectQuery) ([]models.User, error) {
+	var users []models.User
+	query := db.NewSelect().
+		Model(&users).
+		Where("lower(trim(u.block)) = lower(?)", strings.TrimSpace(block)).
+		Where("u.deactivated_at IS NULL")
+	if err := filter(query).Scan(ctx); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// send mails each recipient once, carrying on past failures so one bad address
+// does not stop the rest.
+func send(ctx context.Context, recipients []models.User, subject, body string) error {
+	seen := make(map[string]bool, len(recipients))
+	var errs []error
+	for _, user := range recipients {
+		if seen[user.Email] {
+			continue
+		}
+		seen[user.Email] = true
+		if err := mailer.Default.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: subject,
+			Body:    fmt.Sprintf("Hi %s,\n\n%s", user.Name, body),
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %w", user.Email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func loadRoom(ctx context.Context, db bun.IDB, request *models.Request) error {
+	if request.Room != nil {
+		return nil
+	}
+	request.Room = new(models.Room)
+	return db.NewSelect().Model(request.Room).Where("id = ?", request.RoomID).Scan(ctx)
+}
+
+func loadTypeConfig(ctx context.Context, db bun.IDB, request *models.Request) error {
+	if request.TypeConfig != nil {
+		return nil
+	}
+	request.TypeConfig = new(models.RequestTypeConfig)
+	return db.NewSelect().Model(request.TypeConfig).Where("name = ?", request.Type).Scan(ctx)
+}
+
+func description(request *models.Request) string {
+	if request.Description == nil {
+		return "No description was given."
+	}
+	return "Description: " + *request.Description
+}
+
+func requestLink(request *models.Request) string {
+	return fmt.Sprintf("%s/requests/%d", config.String("APP_BASE_URL", "http://localhost:3000"), request.ID)
+}
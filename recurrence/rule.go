@@ -0,0 +1,204 @@
+// Package recurrence implements the subset of iCalendar RRULE recurrence that
+// request schedules need: daily, weekly and monthly rules with an interval,
+// weekdays for weekly rules, a day of the month for monthly rules and an
+// optional end date. Occurrences are whole days; the time of day is up to the
+// caller.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// searchDays bounds how far ahead Next looks for an occurrence.
+const searchDays = 5 * 366
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule anchored at Start, the date of the first
+// possible occurrence.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+	Until      *time.Time
+	Start      time.Time
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH" anchored at
+// the date of start. Weekly rules without BYDAY repeat on start's weekday and
+// monthly rules without BYMONTHDAY on start's day of the month.
+func Parse(value string, start time.Time) (Rule, error) {
+	rule := Rule{Interval: 1, Start: day(start)}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return Rule{}, fmt.Errorf("malformed rule part %q", part)
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("%s given twice", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(val)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return Rule{}, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 52 {
+				return Rule{}, fmt.Errorf("INTERVAL must be between 1 and 52")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, name := range strings.Split(val, ",") {
+				weekday, ok := weekdays[strings.TrimSpace(name)]
+				if !ok {
+					return Rule{}, fmt.Errorf("unsupported BYDAY value %q", name)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 31 {
+				return Rule{}, fmt.Errorf("BYMONTHDAY must be between 1 and 31")
+			}
+			rule.ByMonthDay = n
+		case "UNTIL":
+			until, err := parseUntil(val, rule.Start.Location())
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Until = &until
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, errors.New("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != Monthly {
+		return Rule{}, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if rule.Freq == Weekly && len(rule.ByDay) == 0 {
+		rule.ByDay = []time.Weekday{rule.Start.Weekday()}
+	}
+	if rule.Freq == Monthly && rule.ByMonthDay == 0 {
+		rule.ByMonthDay = rule.Start.Day()
+	}
+	if rule.Until != nil && rule.Until.Before(rule.Start) {
+		return Rule{}, errors.New("UNTIL is before the first occurrence")
+	}
+	return rule, nil
+}
+
+// Next returns the first occurrence on or after the date of from, or false
+// when the rule has ended.
+func (r Rule) Next(from time.Time) (time.Time, bool) {
+	d := day(from)
+	if d.Before(r.Start) {
+		d = r.Start
+	}
+	for range searchDays {
+		if r.Until != nil && d.After(*r.Until) {
+			return time.Time{}, false
+		}
+		if r.matches(d) {
+			return d, true
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// Occurrences returns up to n occurrences on or after the date of from.
+func (r Rule) Occurrences(from time.Time, n int) []time.Time {
+	var dates []time.Time
+	for len(dates) < n {
+		next, ok := r.Next(from)
+		if !ok {
+			break
+		}
+		dates = append(dates, next)
+		from = next.AddDate(0, 0, 1)
+	}
+	return dates
+}
+
+func (r Rule) matches(d time.Time) bool {
+	switch r.Freq {
+	case Daily:
+		return daysBetween(r.Start, d)%r.Interval == 0
+	case Weekly:
+		weeks := daysBetween(weekStart(r.Start), weekStart(d)) / 7
+		return weeks%r.Interval == 0 && slices.Contains(r.ByDay, d.Weekday())
+	case Monthly:
+		months := (d.Year()-r.Start.Year())*12 + int(d.Month()-r.Start.Month())
+		return months%r.Interval == 0 && d.Day() == r.ByMonthDay
+	}
+	return false
+}
+
+// day truncates t to midnight in its own location.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// weekStart returns the Monday on or before d.
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// daysBetween counts calendar days from a to b, ignoring DST shifts.
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+// parseUntil reads the UNTIL date. Only the date matters since occurrences
+// are whole days.
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
@@ -0,0 +1,171 @@
+package recurrence
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty", ""},
+		{"no freq", "INTERVAL=2"},
+		{"unsupported freq", "FREQ=YEARLY"},
+		{"malformed part", "FREQ=DAILY;INTERVAL"},
+		{"empty value", "FREQ=DAILY;INTERVAL="},
+		{"repeated part", "FREQ=DAILY;FREQ=WEEKLY"},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0"},
+		{"interval too large", "FREQ=WEEKLY;INTERVAL=53"},
+		{"bad weekday", "FREQ=WEEKLY;BYDAY=MO,XX"},
+		{"byday on daily", "FREQ=DAILY;BYDAY=MO"},
+		{"bymonthday zero", "FREQ=MONTHLY;BYMONTHDAY=0"},
+		{"bymonthday 32", "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{"bymonthday on weekly", "FREQ=WEEKLY;BYMONTHDAY=1"},
+		{"bad until", "FREQ=DAILY;UNTIL=tomorrow"},
+		{"until before start", "FREQ=DAILY;UNTIL=20251231"},
+		{"unsupported part", "FREQ=DAILY;COUNT=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.rule, date("2026-01-01")); err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error", tt.rule)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		from  string
+		want  []string
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY",
+			start: "2026-03-01", from: "2026-03-01",
+			want: []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-04"},
+		},
+		{
+			name:  "daily interval",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: "2026-03-01", from: "2026-03-02",
+			want: []string{"2026-03-04", "2026-03-07", "2026-03-10", "2026-03-13"},
+		},
+		{
+			name:  "weekly defaults to the start weekday",
+			rule:  "FREQ=WEEKLY",
+			start: "2026-01-07", from: "2026-01-01",
+			want: []string{"2026-01-07", "2026-01-14", "2026-01-21", "2026-01-28"},
+		},
+		{
+			name:  "fortnightly on two days",
+			rule:  "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			start: "2026-01-07", from: "2026-01-07",
+			want: []string{"2026-01-08", "2026-01-19", "2026-01-22", "2026-02-02"},
+		},
+		{
+			name:  "lower case and spaces",
+			rule:  " freq=weekly; byday=su ",
+			start: "2026-01-01", from: "2026-01-01",
+			want: []string{"2026-01-04", "2026-01-11", "2026-01-18", "2026-01-25"},
+		},
+		{
+			name:  "monthly defaults to the start day",
+			rule:  "FREQ=MONTHLY",
+			start: "2026-01-15", from: "2026-01-15",
+			want: []string{"2026-01-15", "2026-02-15", "2026-03-15", "2026-04-15"},
+		},
+		{
+			name:  "every other month",
+			rule:  "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=15",
+			start: "2026-01-20", from: "2026-01-20",
+			want: []string{"2026-03-15", "2026-05-15", "2026-07-15", "2026-09-15"},
+		},
+		{
+			name:  "day 31 skips shorter months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: "2026-01-01", from: "2026-01-01",
+			want: []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260303",
+			start: "2026-03-01", from: "2026-03-01",
+			want: []string{"2026-03-01", "2026-03-02", "2026-03-03"},
+		},
+		{
+			name:  "until as a UTC timestamp",
+			rule:  "FREQ=WEEKLY;UNTIL=20260115T235959Z",
+			start: "2026-01-01", from: "2026-01-01",
+			want: []string{"2026-01-01", "2026-01-08", "2026-01-15"},
+		},
+		{
+			name:  "from after until",
+			rule:  "FREQ=DAILY;UNTIL=2026-03-03",
+			start: "2026-03-01", from: "2026-03-04",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, date(tt.start))
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rule, err)
+			}
+
+			var got []string
+			for _, d := range rule.Occurrences(date(tt.from), 4) {
+				got = append(got, d.Format(time.DateOnly))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Occurrences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNextAcrossDST checks that daily intervals count calendar days rather
+// than 24-hour periods when the clocks change.
+func TestNextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	start := time.Date(2026, time.March, 6, 0, 0, 0, 0, loc)
+	rule, err := Parse("FREQ=DAILY;INTERVAL=2", start)
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+
+	// Clocks go forward on 8 March 2026.
+	want := []time.Time{
+		time.Date(2026, time.March, 6, 0, 0, 0, 0, loc),
+		time.Date(2026, time.March, 8, 0, 0, 0, 0, loc),
+		time.Date(2026, time.March, 10, 0, 0, 0, 0, loc),
+	}
+	if got := rule.Occurrences(start, 3); !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Fatalf("Occurrences = %v, want %v", got, want)
+	}
+
+	next, ok := rule.Next(time.Date(2026, time.March, 9, 23, 30, 0, 0, loc))
+	if !ok || !next.Equal(want[2]) {
+		t.Fatalf("Next = %v, %v, want %v", next, ok, want[2])
+	}
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+// canAccessRoom reports whether user may view or act on requests for room.
+// Admins see every room, staff and wardens see the rooms in their block and
+// residents see the rooms they are active members of.
+func canAccessRoom(ctx context.Context, db bun.IDB, user *models.User, room *models.Room) (bool, error) {
+	switch {
+	case user.Role == models.RoleAdmin:
+		return true, nil
+	case user.Role.IsStaff():
+		return user.Block != nil && strings.EqualFold(strings.TrimSpace(*user.Block), room.Block), nil
+	default:
+		return db.NewSelect().
+			Model((*models.RoomMember)(nil)).
+			Where("room_id = ?", room.ID).
+			Where("block = ?", room.Block).
+			Where("user_id = ?", user.ID).
+			Where("active").
+			Exists(ctx)
+	}
+}
+
+// scopeRequests restricts a query over requests to the rows user may see,
+// following the same rules as canAccessRoom.
+func scopeRequests(query *bun.SelectQuery, user *models.User) *bun.SelectQuery {
+	return scopeRooms(query, user, "req.room_id")
+}
+
+// scopeRooms restricts a query to the rows whose room, held in column, user
+// may see, following the same rules as canAccessRoom.
+func scopeRooms(query *bun.SelectQuery, user *models.User, column string) *bun.SelectQuery {
+	switch {
+	case user.Role == models.RoleAdmin:
+		return query
+	case user.Role.IsStaff():
+		if user.Block == nil || strings.TrimSpace(*user.Block) == "" {
+			return query.Where("FALSE")
+		}
+		return query.Where("? IN (SELECT id FROM rooms WHERE lower(block) = lower(?))", bun.Ident(column), strings.TrimSpace(*user.Block))
+	default:
+		return query.Where("? IN (SELECT room_id FROM room_members WHERE user_id = ? AND active)", bun.Ident(column), user.ID)
+	}
+}
+
+// isProfileRoom reports whether room matches the block and room the user
+// registered with.
+func isProfileRoom(user *models.User, room *models.Room) bool {
+	if user.Block == nil || user.RoomName == nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(*user.Block), room.Block) &&
+		strings.EqualFold(strings.TrimSpace(*user.RoomName), room.RoomNumber)
+}
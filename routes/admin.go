@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// UnlockUser clears a sign-in lockout for the user in the :id path parameter.
+func UnlockUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	actor := currentUser(c)
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		user := new(models.User)
+		if err := tx.NewSelect().Model(user).Where("id = ?", userID).Scan(ctx); err != nil {
+			return err
+		}
+
+		if err := resetFailedLogins(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return recordSecurityEvent(ctx, tx, &models.SecurityEvent{
+			Type:    models.SecurityEventAccountUnlocked,
+			UserID:  &user.ID,
+			ActorID: &actor.ID,
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("unlock user failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// ListSecurityEvents returns recent lockouts and throttling events, newest
+// first. Wardens only see events for accounts in their block.
+func ListSecurityEvents(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var events []models.SecurityEvent
+	query := database.DB.NewSelect().
+		Model(&events).
+		Relation("User").
+		Order("se.created_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if eventType := strings.TrimSpace(c.Query("type")); eventType != "" {
+		query = query.Where("se.type = ?", eventType)
+	}
+
+	if userParam := strings.TrimSpace(c.Query("user_id")); userParam != "" {
+		userID, err := uuid.Parse(userParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		query = query.Where("se.user_id = ?", userID)
+	}
+
+	user := currentUser(c)
+	if user.Role != models.RoleAdmin {
+		if user.Block == nil || strings.TrimSpace(*user.Block) == "" {
+			query = query.Where("FALSE")
+		} else {
+			query = query.Where("se.user_id IN (SELECT id FROM users WHERE block = ?)", strings.TrimSpace(*user.Block))
+		}
+	}
+
+	total, err := query.ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list security events failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list security events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,576 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// UpdateUserRoleRequest represents the admin role change request body
+type UpdateUserRoleRequest struct {
+	Role models.Role `json:"role" binding:"required"`
+}
+
+var (
+	errUserAlreadyDeactivated = errors.New("user is already deactivated")
+	errUserNotDeactivated     = errors.New("user is not deactivated")
+)
+
+// ListUsers searches users for administrators. Filters: name and email
+// (substring, case-insensitive), block and room (exact, case-insensitive),
+// role and status (active or deactivated), plus limit and offset.
+func ListUsers(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var users []models.User
+	query := database.DB.NewSelect().
+		Model(&users).
+		Order("u.name ASC", "u.created_at ASC").
+		Limit(limit).
+		Offset(offset)
+
+	if name := strings.TrimSpace(c.Query("name")); name != "" {
+		query = query.Where("u.name ILIKE ?", containsPattern(name))
+	}
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		query = query.Where("u.email ILIKE ?", containsPattern(email))
+	}
+	if block := strings.TrimSpace(c.Query("block")); block != "" {
+		query = query.Where("lower(u.block) = lower(?)", block)
+	}
+	if room := strings.TrimSpace(c.Query("room")); room != "" {
+		query = query.Where("lower(u.room_name) = lower(?)", room)
+	}
+
+	if roleParam := strings.TrimSpace(c.Query("role")); roleParam != "" {
+		role := models.Role(roleParam)
+		if !role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+			return
+		}
+		query = query.Where("u.role = ?", role)
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("u.deactivated_at IS NULL")
+	case "deactivated":
+		query = query.Where("u.deactivated_at IS NOT NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or deactivated"})
+		return
+	}
+
+	total, err := query.ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list users failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetUser returns the user in the :id path parameter with their room
+// memberships, including inactive ones.
+func GetUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user := new(models.User)
+	if err := database.DB.NewSelect().Model(user).Where("id = ?", userID).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("user lookup failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var memberships []models.RoomMember
+	if err := database.DB.NewSelect().
+		Model(&memberships).
+		Relation("Room").
+		Where("rm.user_id = ?", userID).
+		Order("rm.joined_at ASC").
+		Scan(ctx); err != nil {
+		logger(c).Error("room membership lookup failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":             user,
+		"room_memberships": memberships,
+	})
+}
+
+// DeactivateUser blocks the user in the :id path parameter from signing in.
+// Their sessions and API keys are revoked and their room memberships are kept
+// but flagged inactive.
+func DeactivateUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := currentUser(c)
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		user, err := lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.Active() {
+			return errUserAlreadyDeactivated
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(user).
+			Set("deactivated_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+		if err := setRoomMembershipsActive(ctx, tx, user.ID, false); err != nil {
+			return err
+		}
+		if err := revokeUserSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if err := revokeUserAPIKeys(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return recordSecurityEvent(ctx, tx, &models.SecurityEvent{
+			Type:    models.SecurityEventUserDeactivated,
+			UserID:  &user.ID,
+			ActorID: &actor.ID,
+		})
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case errors.Is(err, errUserAlreadyDeactivated):
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already deactivated"})
+		default:
+			logger(c).Error("deactivate user failed", "target_user_id", userID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deactivated"})
+}
+
+// ReactivateUser lets a deactivated user sign in again and restores their
+// room memberships. Revoked API keys stay revoked.
+func ReactivateUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := currentUser(c)
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		user, err := lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Active() {
+			return errUserNotDeactivated
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(user).
+			Set("deactivated_at = NULL").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+		if err := setRoomMembershipsActive(ctx, tx, user.ID, true); err != nil {
+			return err
+		}
+
+		return recordSecurityEvent(ctx, tx, &models.SecurityEvent{
+			Type:    models.SecurityEventUserReactivated,
+			UserID:  &user.ID,
+			ActorID: &actor.ID,
+		})
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case errors.Is(err, errUserNotDeactivated):
+			c.JSON(http.StatusConflict, gin.H{"error": "User is not deactivated"})
+		default:
+			logger(c).Error("reactivate user failed", "target_user_id", userID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reactivate user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User reactivated"})
+}
+
+// UpdateUserRole changes the role of the user in the :id path parameter.
+func UpdateUserRole(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	actor := currentUser(c)
+	updated := new(models.User)
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		user, err := lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		*updated = *user
+		if user.Role == req.Role {
+			return nil
+		}
+
+		updated.Role = req.Role
+		if _, err := tx.NewUpdate().
+			Model(updated).
+			Column("role").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		details := fmt.Sprintf("role changed from %s to %s", user.Role, req.Role)
+		return recordSecurityEvent(ctx, tx, &models.SecurityEvent{
+			Type:    models.SecurityEventRoleChanged,
+			UserID:  &user.ID,
+			ActorID: &actor.ID,
+			Details: &details,
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("update user role failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated",
+		"user":    updated,
+	})
+}
+
+// ForcePasswordReset replaces the password of the user in the :id path
+// parameter with an unusable one, signs them out everywhere and emails them a
+// reset link once the reset is saved.
+func ForcePasswordReset(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+
+	randomPassword, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		logger(c).Error("random password generation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	hashedPassword, err := hashPassword(randomPassword)
+	if err != nil {
+		logger(c).Error("password hash failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	actor := currentUser(c)
+	var user *models.User
+	var token string
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		user, err = lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(user).
+			Set("password = ?", hashedPassword).
+			Set("password_changed_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+		if err := revokeUserSessions(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if err := recordSecurityEvent(ctx, tx, &models.SecurityEvent{
+			Type:    models.SecurityEventPasswordReset,
+			UserID:  &user.ID,
+			ActorID: &actor.ID,
+		}); err != nil {
+			return err
+		}
+
+		token, err = issuePasswordReset(ctx, tx, user)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("force password reset failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Mailed after commit so a slow mail server never holds the user row
+	// lock. The reset stands either way; running it again sends a new link.
+	if err := mailPasswordReset(ctx, user, token); err != nil {
+		logger(c).Error("password reset email failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Password reset, but the reset email could not be sent; reset again to resend it",
+			"email_sent": false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Password reset; the user has been emailed a reset link",
+		"email_sent": true,
+	})
+}
+
+// targetUserID parses the :id path parameter and rejects administrators
+// acting on their own account, which could lock them out.
+func targetUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return uuid.Nil, false
+	}
+	if userID == currentUser(c).ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Administrators cannot change their own account here"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// lockUser loads a user for update.
+func lockUser(ctx context.Context, tx bun.Tx, userID uuid.UUID) (*models.User, error) {
+	user := new(models.User)
+	if err := tx.NewSelect().Model(user).Where("id = ?", userID).For("UPDATE").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// containsPattern returns an ILIKE pattern matching value anywhere, with
+// wildcard characters in value escaped.
+func containsPattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
+
+type UpdateOnCallRequest struct {
+	OnCall *bool `json:"on_call" binding:"required"`
+}
+
+// UpdateOnCall puts a technician on or takes them off the emergency rota.
+// Wardens may only manage technicians in their own block.
+func UpdateOnCall(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req UpdateOnCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor := currentUser(c)
+	user := new(models.User)
+	if err := database.DB.NewSelect().Model(user).Where("id = ?", userID).Scan(c.Request.Context()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("load user failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update on-call status"})
+		return
+	}
+
+	if actor.Role != models.RoleAdmin &&
+		(actor.Block == nil || user.Block == nil || !strings.EqualFold(strings.TrimSpace(*actor.Block), strings.TrimSpace(*user.Block))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wardens can only manage technicians in their block"})
+		return
+	}
+	if user.Role != models.RoleTechnician {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only technicians can be put on call"})
+		return
+	}
+
+	user.OnCall = *req.OnCall
+	if _, err := database.DB.NewUpdate().
+		Model(user).
+		Column("on_call").
+		WherePK().
+		Exec(c.Request.Context()); err != nil {
+		logger(c).Error("update on-call status failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update on-call status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "On-call status updated",
+		"user":    user,
+	})
+}
+
+type MoveUserRoomRequest struct {
+	Block    string `json:"block" binding:"required"`
+	RoomName string `json:"room_name" binding:"required"`
+}
+
+var errNotResident = errors.New("user is not a resident")
+
+// MoveUserRoom moves a resident to another room, swapping their room
+// membership. Wardens may only move residents of their block to rooms in it.
+func MoveUserRoom(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req MoveUserRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	block := strings.TrimSpace(req.Block)
+	roomName := strings.TrimSpace(req.RoomName)
+	if block == "" || roomName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "block and room_name are required"})
+		return
+	}
+
+	actor := currentUser(c)
+	user := new(models.User)
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		locked, err := lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		*user = *locked
+		if user.Role != models.RoleResident {
+			return errNotResident
+		}
+
+		if actor.Role != models.RoleAdmin && (actor.Block == nil || user.Block == nil ||
+			!strings.EqualFold(strings.TrimSpace(*actor.Block), strings.TrimSpace(*user.Block)) ||
+			!strings.EqualFold(strings.TrimSpace(*actor.Block), block)) {
+			return errRoomForbidden
+		}
+
+		previous := *user
+		user.Block = &block
+		user.RoomName = &roomName
+		if _, err := tx.NewUpdate().
+			Model(user).
+			Column("block", "room_name").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt == nil || sameProfileRoom(&previous, user) {
+			return nil
+		}
+		if err := unlinkProfileRoom(ctx, tx, &previous); err != nil {
+			return err
+		}
+		return linkProfileRoom(ctx, tx, user)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case errors.Is(err, errNotResident):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only residents can be moved between rooms"})
+		case errors.Is(err, errRoomForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Wardens can only move residents within their block"})
+		default:
+			logger(c).Error("move user room failed", "target_user_id", userID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User moved",
+		"user":    user,
+	})
+}
@@ -0,0 +1,251 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// CreateAPIKeyRequest represents the API key creation request body
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse carries a newly created key. The plaintext key is only
+// ever returned here.
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+// CreateAPIKey issues a personal API key for the current user.
+func CreateAPIKey(c *gin.Context) {
+	user := currentUser(c)
+	createAPIKey(c, user.ID, models.APIKeyKindPersonal, nil)
+}
+
+// CreateServiceAPIKey issues a service API key owned by the user in the :id
+// path parameter, for integrations that act as that account.
+func CreateServiceAPIKey(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	exists, err := database.DB.NewSelect().
+		Model((*models.User)(nil)).
+		Where("id = ?", userID).
+		Exists(c.Request.Context())
+	if err != nil {
+		logger(c).Error("user lookup failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	actor := currentUser(c)
+	createAPIKey(c, userID, models.APIKeyKindService, &actor.ID)
+}
+
+func createAPIKey(c *gin.Context, ownerID uuid.UUID, kind models.APIKeyKind, createdBy *uuid.UUID) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	var scopes []string
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(models.APIScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Unknown scope: " + scope,
+				"scopes": models.APIScopes,
+			})
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "At least one scope is required",
+			"scopes": models.APIScopes,
+		})
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		logger(c).Error("api key generation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	apiKey := &models.APIKey{
+		UserID:    ownerID,
+		Name:      name,
+		Kind:      kind,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: createdBy,
+	}
+	if _, err := database.DB.NewInsert().Model(apiKey).Returning("*").Exec(c.Request.Context()); err != nil {
+		logger(c).Error("insert api key failed", "owner_id", ownerID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{Key: key, APIKey: apiKey})
+}
+
+// ListAPIKeys returns the current user's personal API keys, newest first.
+func ListAPIKeys(c *gin.Context) {
+	user := currentUser(c)
+
+	var keys []models.APIKey
+	if err := database.DB.NewSelect().
+		Model(&keys).
+		Where("ak.user_id = ?", user.ID).
+		Where("ak.kind = ?", models.APIKeyKindPersonal).
+		Order("ak.created_at DESC").
+		Scan(c.Request.Context()); err != nil {
+		logger(c).Error("list api keys failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// RevokeAPIKey revokes one of the current user's personal API keys.
+func RevokeAPIKey(c *gin.Context) {
+	user := currentUser(c)
+	revokeAPIKeyHandler(c, func(q *bun.UpdateQuery) *bun.UpdateQuery {
+		return q.Where("user_id = ?", user.ID).Where("kind = ?", models.APIKeyKindPersonal)
+	})
+}
+
+// AdminRevokeAPIKey revokes any API key.
+func AdminRevokeAPIKey(c *gin.Context) {
+	revokeAPIKeyHandler(c, func(q *bun.UpdateQuery) *bun.UpdateQuery { return q })
+}
+
+func revokeAPIKeyHandler(c *gin.Context, scope func(*bun.UpdateQuery) *bun.UpdateQuery) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key id"})
+		return
+	}
+
+	res, err := scope(database.DB.NewUpdate().
+		Model((*models.APIKey)(nil)).
+		Set("revoked_at = COALESCE(revoked_at, now())").
+		Where("id = ?", keyID)).
+		Exec(c.Request.Context())
+	if err != nil {
+		logger(c).Error("revoke api key failed", "api_key_id", keyID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// ListAllAPIKeys returns API keys across all users, newest first. Filter with
+// ?user_id=, ?kind= and ?active=true.
+func ListAllAPIKeys(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	query := database.DB.NewSelect().
+		Model(&keys).
+		Relation("User").
+		Order("ak.created_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if userParam := strings.TrimSpace(c.Query("user_id")); userParam != "" {
+		userID, err := uuid.Parse(userParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		query = query.Where("ak.user_id = ?", userID)
+	}
+
+	if kind := strings.TrimSpace(c.Query("kind")); kind != "" {
+		query = query.Where("ak.kind = ?", kind)
+	}
+
+	if c.Query("active") == "true" {
+		query = query.
+			Where("ak.revoked_at IS NULL").
+			Where("ak.expires_at IS NULL OR ak.expires_at > now()")
+	}
+
+	total, err := query.ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list api keys failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// revokeUserAPIKeys revokes every API key owned by userID.
+func revokeUserAPIKeys(ctx context.Context, db bun.IDB, userID uuid.UUID) error {
+	_, err := db.NewUpdate().
+		Model((*models.APIKey)(nil)).
+		Set("revoked_at = now()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
@@ -1,18 +1,18 @@
 package routes
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"errors"
-	"io"
-	"log"
+	"fmt"
 	"net/http"
 
+	"github.com/adii2ma/dbms-backend/auth"
 	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/logging"
 	"github.com/adii2ma/dbms-backend/models"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 // SignUpRequest represents the signup request body
@@ -37,31 +37,33 @@ type SignUpResponse struct {
 	User    *models.User `json:"user"`
 }
 
-// SignInResponse represents the signin response
+// SignInResponse represents the signin response. When the account has
+// two-factor authentication enabled only ChallengeToken is set, and the
+// client must finish with SignInTwoFactor.
 type SignInResponse struct {
-	Message string       `json:"message"`
-	User    *models.User `json:"user"`
-	Success bool         `json:"success"`
+	Message                string       `json:"message"`
+	User                   *models.User `json:"user,omitempty"`
+	Success                bool         `json:"success"`
+	TwoFactorRequired      bool         `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool         `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string       `json:"challenge_token,omitempty"`
+	*TokenResponse
 }
 
 // SignUp handles user registration
 func SignUp(c *gin.Context) {
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		log.Printf("[SignUp] failed to read request body: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
+	if !selfSignupEnabled() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Self-signup is disabled. Ask your warden for an invitation.",
 		})
 		return
 	}
-	log.Printf("[SignUp] raw request body: %s", string(bodyBytes))
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 	var req SignUpRequest
 
 	// Validate request body
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[SignUp] validation error: %v", err)
+		logger(c).Debug("signup validation failed", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request",
 			"details": err.Error(),
@@ -70,14 +72,14 @@ func SignUp(c *gin.Context) {
 	}
 
 	// Check if user already exists
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	exists, err := database.DB.NewSelect().
 		Model((*models.User)(nil)).
 		Where("email = ?", req.Email).
 		Exists(ctx)
 
 	if err != nil {
-		log.Printf("[SignUp] database exists check failed: %v", err)
+		logger(c).Error("signup email check failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Database error",
 		})
@@ -85,7 +87,7 @@ func SignUp(c *gin.Context) {
 	}
 
 	if exists {
-		log.Printf("[SignUp] duplicate email attempted: %s", req.Email)
+		logger(c).Info("signup with registered email rejected")
 		c.JSON(http.StatusConflict, gin.H{
 			"error": "User with this email already exists",
 		})
@@ -93,9 +95,9 @@ func SignUp(c *gin.Context) {
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
-		log.Printf("[SignUp] password hash failed: %v", err)
+		logger(c).Error("password hash failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to process password",
 		})
@@ -106,16 +108,24 @@ func SignUp(c *gin.Context) {
 	user := &models.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		Block:    req.Block,
 		RoomName: req.RoomName,
 		Phone:    req.Phone,
 	}
 
-	// Start a transaction so we create user, room and room_member atomically
+	if req.RoomName != nil && *req.RoomName != "" && (req.Block == nil || *req.Block == "") {
+		logger(c).Debug("signup room_name provided without block")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "block is required when room_name is provided"})
+		return
+	}
+
+	// Start a transaction so we create the user and its verification token
+	// atomically. The room_members link is deferred until the email is
+	// verified, see VerifyEmail.
 	tx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
-		log.Printf("[SignUp] failed to begin tx: %v", err)
+		logger(c).Error("begin transaction failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
@@ -127,92 +137,45 @@ func SignUp(c *gin.Context) {
 	// Insert user and get generated ID
 	_, err = tx.NewInsert().Model(user).Returning("id").Exec(ctx)
 	if err != nil {
-		log.Printf("[SignUp] insert user failed: %v", err)
+		logger(c).Error("insert user failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
 		return
 	}
 
-	// If room name provided, create/find room and add room_member
-	if req.RoomName != nil && *req.RoomName != "" {
-		if req.Block == nil || *req.Block == "" {
-			log.Printf("[SignUp] room_name provided without block")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "block is required when room_name is provided"})
-			return
-		}
-
-		room := new(models.Room)
-		err = tx.NewSelect().
-			Model(room).
-			Where("room_number = ?", *req.RoomName).
-			Where("block = ?", *req.Block).
-			Scan(ctx)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				// create room
-				room = &models.Room{
-					Block:      *req.Block,
-					RoomNumber: *req.RoomName,
-				}
-				_, err = tx.NewInsert().Model(room).Returning("id").Exec(ctx)
-				if err != nil {
-					log.Printf("[SignUp] create room failed: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
-					return
-				}
-			} else {
-				log.Printf("[SignUp] find room failed: %v", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-				return
-			}
-		}
-
-		// create room_member linking user and room
-		rm := &models.RoomMember{
-			RoomID: room.ID,
-			Block:  room.Block,
-			UserID: user.ID,
-		}
-		_, err = tx.NewInsert().Model(rm).Exec(ctx)
-		if err != nil {
-			log.Printf("[SignUp] create room_member failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add room member"})
-			return
-		}
+	verificationToken, err := createEmailVerificationToken(ctx, tx, user.ID)
+	if err != nil {
+		logger(c).Error("create verification token failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
 	}
 
 	// commit transaction
 	if err := tx.Commit(); err != nil {
-		log.Printf("[SignUp] commit failed: %v", err)
+		logger(c).Error("commit failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database commit failed"})
 		return
 	}
 
-	log.Printf("[SignUp] user created successfully: %s", req.Email)
+	if err := sendVerificationEmail(ctx, user, verificationToken); err != nil {
+		// The user can ask for a new link, so signup still succeeds.
+		logger(c).Error("send verification email failed", "user_id", user.ID, "error", err)
+	}
+
+	logger(c).Info("user signed up", "user_id", user.ID)
 	// Return success response
 	c.JSON(http.StatusCreated, SignUpResponse{
-		Message: "User registered successfully",
+		Message: "User registered successfully. Check your email to verify your account.",
 		User:    user,
 	})
 }
 
 // SignIn handles user login
 func SignIn(c *gin.Context) {
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		log.Printf("[SignIn] failed to read request body: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-		})
-		return
-	}
-	log.Printf("[SignIn] raw request body: %s", string(bodyBytes))
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
 	var req SignInRequest
 
 	// Validate request body
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[SignIn] validation error: %v", err)
+		logger(c).Debug("signin validation failed", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request",
 			"details": err.Error(),
@@ -220,8 +183,29 @@ func SignIn(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	policy := currentLoginPolicy()
+
+	ipFailures, err := ipFailureCount(ctx, database.DB, ip, policy.IPWindow)
+	if err != nil {
+		logger(c).Error("ip failure count failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"success": false,
+		})
+		return
+	}
+	if policy.IPMaxFailures > 0 && ipFailures >= policy.IPMaxFailures {
+		setRetryAfter(c, policy.IPWindow)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":   "Too many failed sign-in attempts from this address. Try again later.",
+			"success": false,
+		})
+		return
+	}
+
 	// Find user by email
-	ctx := context.Background()
 	user := new(models.User)
 	err = database.DB.NewSelect().
 		Model(user).
@@ -229,7 +213,20 @@ func SignIn(c *gin.Context) {
 		Scan(ctx)
 
 	if err != nil {
-		log.Printf("[SignIn] user lookup failed for %s: %v", req.Email, err)
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger(c).Error("signin user lookup failed", "error", err)
+		}
+		recordSignInFailure(ctx, req.Email, nil, ip, ipFailures, policy)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid email or password",
+			"success": false,
+		})
+		return
+	}
+
+	if reason := loginBlocked(user, policy); reason != "" {
+		logger(c).Info("signin blocked", "user_id", user.ID, "reason", reason)
+		recordSignInFailure(ctx, req.Email, &user.ID, ip, ipFailures, policy)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Invalid email or password",
 			"success": false,
@@ -238,9 +235,15 @@ func SignIn(c *gin.Context) {
 	}
 
 	// Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	ok, needsRehash, err := auth.VerifyPassword(user.Password, req.Password)
 	if err != nil {
-		log.Printf("[SignIn] password mismatch for %s: %v", req.Email, err)
+		logger(c).Error("password verification failed", "user_id", user.ID, "error", err)
+	}
+	if !ok {
+		if _, err := recordFailedLogin(ctx, database.DB, user, ip, policy); err != nil {
+			logger(c).Error("record failed login failed", "user_id", user.ID, "error", err)
+		}
+		recordSignInFailure(ctx, req.Email, &user.ID, ip, ipFailures, policy)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "Invalid email or password",
 			"success": false,
@@ -248,11 +251,152 @@ func SignIn(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[SignIn] login successful for %s", req.Email)
+	if needsRehash {
+		rehashPassword(c, user, req.Password)
+	}
+
+	finishSignIn(c, user, req.Email, ip)
+}
+
+// rehashPassword replaces a stored hash that is weaker than the current
+// policy. password_changed_at is left alone so existing sessions survive.
+// Failures are logged; the old hash keeps working.
+func rehashPassword(c *gin.Context, user *models.User, password string) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		logger(c).Error("password rehash failed", "user_id", user.ID, "error", err)
+		return
+	}
+
+	if _, err := database.DB.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("password = ?", hashed).
+		Where("id = ?", user.ID).
+		Where("password = ?", user.Password).
+		Exec(c.Request.Context()); err != nil {
+		logger(c).Error("store rehashed password failed", "user_id", user.ID, "error", err)
+		return
+	}
+	user.Password = hashed
+	logger(c).Info("password rehashed to current policy", "user_id", user.ID)
+}
+
+// finishSignIn runs once the user has proven their primary credential. It
+// answers with a two-factor challenge when the account has TOTP enabled and
+// opens a session otherwise.
+func finishSignIn(c *gin.Context, user *models.User, email, ip string) {
+	if rejectDeactivated(c, user) {
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := auth.IssueTwoFactorChallenge(user.ID)
+		if err != nil {
+			logger(c).Error("issue two-factor challenge failed", "user_id", user.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to issue tokens",
+				"success": false,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, SignInResponse{
+			Message:           "Two-factor authentication required",
+			Success:           false,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
+	completeSignIn(c, user, email, ip)
+}
+
+// completeSignIn clears failed attempts, opens a session and writes the
+// signin response once every factor has been checked.
+func completeSignIn(c *gin.Context, user *models.User, email, ip string) {
+	ctx := c.Request.Context()
+
+	if rejectDeactivated(c, user) {
+		return
+	}
+
+	if err := resetFailedLogins(ctx, database.DB, user.ID); err != nil {
+		logger(c).Error("reset failed logins failed", "user_id", user.ID, "error", err)
+	}
+	if err := recordLoginAttempt(ctx, database.DB, email, &user.ID, ip, true); err != nil {
+		logging.FromContext(ctx).Error("record login attempt failed", "error", err)
+	}
+
+	tokens, err := startSession(ctx, database.DB, user.ID, c.Request.UserAgent(), ip)
+	if err != nil {
+		logger(c).Error("start session failed", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to issue tokens",
+			"success": false,
+		})
+		return
+	}
+
+	logger(c).Info("user signed in", "user_id", user.ID)
 	// Return success response
 	c.JSON(http.StatusOK, SignInResponse{
-		Message: "Login successful",
-		User:    user,
-		Success: true,
+		Message:                "Login successful",
+		User:                   user,
+		Success:                true,
+		TwoFactorSetupRequired: twoFactorRequired(user.Role) && user.TOTPEnabledAt == nil,
+		TokenResponse:          tokens,
 	})
 }
+
+// rejectDeactivated writes a 403 response and returns true when an
+// administrator has deactivated the account. It runs only after the
+// credentials check so it does not reveal which accounts exist.
+func rejectDeactivated(c *gin.Context, user *models.User) bool {
+	if user.Active() {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "This account has been deactivated. Contact an administrator.",
+		"success": false,
+	})
+	return true
+}
+
+// hashPassword returns the hash stored in users.password under the current
+// password policy.
+func hashPassword(password string) (string, error) {
+	return auth.HashPassword(password)
+}
+
+// checkPassword reports whether password matches the user's stored hash.
+// Hashes that cannot be parsed are logged and treated as a mismatch.
+func checkPassword(c *gin.Context, user *models.User, password string) bool {
+	ok, _, err := auth.VerifyPassword(user.Password, password)
+	if err != nil {
+		logger(c).Error("password verification failed", "user_id", user.ID, "error", err)
+		return false
+	}
+	return ok
+}
+
+// recordSignInFailure stores a failed attempt and raises a security event the
+// moment the client address crosses the throttling threshold.
+func recordSignInFailure(ctx context.Context, email string, userID *uuid.UUID, ip string, priorIPFailures int, policy loginPolicy) {
+	if err := recordLoginAttempt(ctx, database.DB, email, userID, ip, false); err != nil {
+		logging.FromContext(ctx).Error("record login attempt failed", "error", err)
+	}
+
+	if policy.IPMaxFailures <= 0 || priorIPFailures+1 != policy.IPMaxFailures {
+		return
+	}
+
+	details := fmt.Sprintf("%d failed sign-in attempts within %s", policy.IPMaxFailures, policy.IPWindow)
+	if err := recordSecurityEvent(ctx, database.DB, &models.SecurityEvent{
+		Type:    models.SecurityEventIPThrottled,
+		IP:      &ip,
+		Details: &details,
+	}); err != nil {
+		logging.FromContext(ctx).Error("record security event failed", "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/mailer"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// VerifyEmail consumes a verification token from the signup email, marks the
+// account verified and links it into the room it registered with.
+func VerifyEmail(c *gin.Context) {
+	token := strings.TrimSpace(c.Query("token"))
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token query parameter is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		record := new(models.EmailVerificationToken)
+		if err := tx.NewSelect().
+			Model(record).
+			Where("token_hash = ?", auth.HashToken(token)).
+			Where("used_at IS NULL").
+			Where("expires_at > now()").
+			For("UPDATE").
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidVerificationToken
+			}
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(record).
+			Set("used_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		user := new(models.User)
+		if err := tx.NewSelect().Model(user).Where("id = ?", record.UserID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(user).
+			Set("email_verified_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		return linkProfileRoom(ctx, tx, user)
+	})
+
+	if err != nil {
+		if errors.Is(err, errInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		logger(c).Error("email verification failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification emails a fresh verification link to the signed-in user.
+func ResendVerification(c *gin.Context) {
+	user := currentUser(c)
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := createEmailVerificationToken(ctx, database.DB, user.ID)
+	if err != nil {
+		logger(c).Error("create verification token failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification link"})
+		return
+	}
+
+	if err := sendVerificationEmail(ctx, user, token); err != nil {
+		logger(c).Error("send verification email failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// RequireVerifiedEmail rejects users who have not confirmed their email
+// address. It must run after RequireAuth.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil || user.EmailVerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Verify your email address before continuing",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// createEmailVerificationToken invalidates outstanding verification tokens
+// for the user and stores a new one, returning the plaintext token.
+func createEmailVerificationToken(ctx context.Context, db bun.IDB, userID uuid.UUID) (string, error) {
+	if _, err := db.NewUpdate().
+		Model((*models.EmailVerificationToken)(nil)).
+		Set("used_at = now()").
+		Where("user_id = ?", userID).
+		Where("used_at IS NULL").
+		Exec(ctx); err != nil {
+		return "", err
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := &models.EmailVerificationToken{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour)),
+	}
+	if _, err := db.NewInsert().Model(record).Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func sendVerificationEmail(ctx context.Context, user *models.User, token string) error {
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.String("APP_BASE_URL", "http://localhost:3000"), url.QueryEscape(token))
+	return mailer.Default.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address to start filing requests:\n\n%s\n\nIf you did not create an account, you can ignore this email.",
+			user.Name, link),
+	})
+}
@@ -0,0 +1,438 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/mailer"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+// CreateInvitationRequest represents the invitation request body. The room is
+// given either by room_id or by block and room_number.
+type CreateInvitationRequest struct {
+	Email      string      `json:"email" binding:"required,email"`
+	RoomID     *int        `json:"room_id"`
+	Block      string      `json:"block"`
+	RoomNumber string      `json:"room_number"`
+	Role       models.Role `json:"role"`
+}
+
+// AcceptInvitationRequest represents the invitation redemption request body
+type AcceptInvitationRequest struct {
+	Token    string  `json:"token" binding:"required"`
+	Name     string  `json:"name" binding:"required"`
+	Password string  `json:"password" binding:"required,min=6"`
+	Phone    *string `json:"phone"`
+}
+
+var (
+	errInvalidInvitation = errors.New("invalid or expired invitation")
+	errEmailRegistered   = errors.New("email is already registered")
+)
+
+// selfSignupEnabled reports whether anyone may create an account through
+// SignUp or a first single sign-on login. When it is off, accounts are only
+// created from invitations.
+func selfSignupEnabled() bool {
+	return config.Bool("SELF_SIGNUP_ENABLED", true)
+}
+
+// CreateInvitation emails an invitation to join a room. Wardens may invite
+// residents to rooms in their block; administrators may invite any role to
+// any room.
+func CreateInvitation(c *gin.Context) {
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	inviter := currentUser(c)
+	email := strings.TrimSpace(req.Email)
+	role := req.Role
+	if role == "" {
+		role = models.RoleResident
+	}
+	if !role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if inviter.Role != models.RoleAdmin && role != models.RoleResident {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wardens can only invite residents"})
+		return
+	}
+
+	block := strings.TrimSpace(req.Block)
+	roomNumber := strings.TrimSpace(req.RoomNumber)
+	if req.RoomID == nil && (block == "" || roomNumber == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "room_id or block and room_number is required"})
+		return
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		logger(c).Error("invitation token generation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	invitation := &models.Invitation{
+		Email:     email,
+		Role:      role,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(config.Duration("INVITATION_TTL", 7*24*time.Hour)),
+		InvitedBy: &inviter.ID,
+	}
+
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		room := new(models.Room)
+		if req.RoomID != nil {
+			if err := tx.NewSelect().Model(room).Where("id = ?", *req.RoomID).Scan(ctx); err != nil {
+				return err
+			}
+			if block != "" && !strings.EqualFold(room.Block, block) {
+				return errRoomBlockMismatch
+			}
+		} else {
+			allowed, err := canAccessRoom(ctx, tx, inviter, &models.Room{Block: block})
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return errRoomForbidden
+			}
+			if room, err = findOrCreateRoom(ctx, tx, block, roomNumber); err != nil {
+				return err
+			}
+		}
+
+		allowed, err := canAccessRoom(ctx, tx, inviter, room)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errRoomForbidden
+		}
+
+		registered, err := tx.NewSelect().
+			Model((*models.User)(nil)).
+			Where("lower(email) = lower(?)", email).
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return errEmailRegistered
+		}
+
+		// Only the newest invitation for an address can be redeemed.
+		if _, err := tx.NewUpdate().
+			Model((*models.Invitation)(nil)).
+			Set("revoked_at = now()").
+			Where("lower(email) = lower(?)", email).
+			Where("accepted_at IS NULL").
+			Where("revoked_at IS NULL").
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		invitation.RoomID = room.ID
+		invitation.Block = room.Block
+		invitation.Room = room
+		_, err = tx.NewInsert().Model(invitation).Returning("*").Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		case errors.Is(err, errRoomBlockMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Room does not belong to the provided block"})
+		case errors.Is(err, errRoomForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only invite users to rooms in your block"})
+		case errors.Is(err, errEmailRegistered):
+			c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+		default:
+			logger(c).Error("create invitation failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		}
+		return
+	}
+
+	// Mailed after commit so a slow mail server never holds the
+	// transaction open. Inviting the address again sends a fresh link.
+	if err := sendInvitationEmail(ctx, invitation, inviter, token); err != nil {
+		logger(c).Error("invitation email failed", "invitation_id", invitation.ID, "error", err)
+		c.JSON(http.StatusCreated, gin.H{
+			"message":    "Invitation created, but the email could not be sent; invite the address again to resend it",
+			"invitation": invitation,
+			"email_sent": false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation sent",
+		"invitation": invitation,
+		"email_sent": true,
+	})
+}
+
+// ListInvitations returns invitations newest first. Wardens only see
+// invitations for their block. Filter with ?status=pending|accepted|revoked|expired.
+func ListInvitations(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var invitations []models.Invitation
+	query := database.DB.NewSelect().
+		Model(&invitations).
+		Relation("Room").
+		Order("inv.created_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	switch c.Query("status") {
+	case "":
+	case "pending":
+		query = query.Where("inv.accepted_at IS NULL AND inv.revoked_at IS NULL AND inv.expires_at > now()")
+	case "accepted":
+		query = query.Where("inv.accepted_at IS NOT NULL")
+	case "revoked":
+		query = query.Where("inv.revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("inv.accepted_at IS NULL AND inv.revoked_at IS NULL AND inv.expires_at <= now()")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, accepted, revoked or expired"})
+		return
+	}
+
+	user := currentUser(c)
+	if user.Role != models.RoleAdmin {
+		if user.Block == nil || strings.TrimSpace(*user.Block) == "" {
+			query = query.Where("FALSE")
+		} else {
+			query = query.Where("lower(trim(inv.block)) = lower(?)", strings.TrimSpace(*user.Block))
+		}
+	}
+
+	total, err := query.ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list invitations failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// RevokeInvitation cancels a pending invitation. Wardens may only revoke
+// invitations for their block.
+func RevokeInvitation(c *gin.Context) {
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation id"})
+		return
+	}
+
+	query := database.DB.NewUpdate().
+		Model((*models.Invitation)(nil)).
+		Set("revoked_at = now()").
+		Where("id = ?", invitationID).
+		Where("accepted_at IS NULL").
+		Where("revoked_at IS NULL")
+
+	user := currentUser(c)
+	if user.Role != models.RoleAdmin {
+		query = query.Where("lower(trim(block)) = lower(?)", strings.TrimSpace(derefString(user.Block)))
+	}
+
+	res, err := query.Exec(c.Request.Context())
+	if err != nil {
+		logger(c).Error("revoke invitation failed", "invitation_id", invitationID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// GetInvitation shows what a pending invitation is for, so the signup page
+// can display the room before the user redeems it.
+func GetInvitation(c *gin.Context) {
+	token := strings.TrimSpace(c.Query("token"))
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token query parameter is required"})
+		return
+	}
+
+	invitation := new(models.Invitation)
+	if err := database.DB.NewSelect().
+		Model(invitation).
+		Relation("Room").
+		Where("inv.token_hash = ?", auth.HashToken(token)).
+		Scan(c.Request.Context()); err != nil || !invitation.Pending() {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger(c).Error("invitation lookup failed", "error", err)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":      invitation.Email,
+		"role":       invitation.Role,
+		"room":       invitation.Room,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation redeems an invitation. The account, its room membership
+// and the redemption are written in one transaction. The email counts as
+// verified because the token was delivered to it.
+func AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		logger(c).Error("password hash failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	user := new(models.User)
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		invitation := new(models.Invitation)
+		if err := tx.NewSelect().
+			Model(invitation).
+			Relation("Room").
+			Where("inv.token_hash = ?", auth.HashToken(req.Token)).
+			For("UPDATE OF inv").
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidInvitation
+			}
+			return err
+		}
+		if !invitation.Pending() || invitation.Room == nil {
+			return errInvalidInvitation
+		}
+
+		registered, err := tx.NewSelect().
+			Model((*models.User)(nil)).
+			Where("lower(email) = lower(?)", invitation.Email).
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return errEmailRegistered
+		}
+
+		now := time.Now()
+		room := invitation.Room
+		*user = models.User{
+			Name:            name,
+			Email:           invitation.Email,
+			Password:        hashedPassword,
+			Block:           &room.Block,
+			Phone:           optionalString(derefString(req.Phone)),
+			Role:            invitation.Role,
+			EmailVerifiedAt: &now,
+		}
+		// Staff are assigned to the block; only residents live in the room.
+		if invitation.Role == models.RoleResident {
+			user.RoomName = &room.RoomNumber
+		}
+		if _, err := tx.NewInsert().Model(user).Returning("*").Exec(ctx); err != nil {
+			return err
+		}
+
+		if invitation.Role == models.RoleResident {
+			if err := ensureRoomMember(ctx, tx, room, user.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.NewUpdate().
+			Model(invitation).
+			Set("accepted_at = now()").
+			Set("accepted_user_id = ?", user.ID).
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidInvitation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		case errors.Is(err, errEmailRegistered):
+			c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+		default:
+			logger(c).Error("accept invitation failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		}
+		return
+	}
+
+	logger(c).Info("user joined from invitation", "user_id", user.ID)
+	c.JSON(http.StatusCreated, SignUpResponse{
+		Message: "Account created. You can now sign in.",
+		User:    user,
+	})
+}
+
+func sendInvitationEmail(ctx context.Context, invitation *models.Invitation, inviter *models.User, token string) error {
+	link := fmt.Sprintf("%s/accept-invite?token=%s", config.String("APP_BASE_URL", "http://localhost:3000"), url.QueryEscape(token))
+	return mailer.Default.Send(ctx, mailer.Message{
+		To:      invitation.Email,
+		Subject: "You're invited to the hostel portal",
+		Body: fmt.Sprintf("Hi,\n\n%s has invited you to join as a %s for room %s in block %s. Use the link below to create your account. It expires on %s.\n\n%s\n\nIf you were not expecting this, you can ignore this email.",
+			inviter.Name, invitation.Role, invitation.Room.RoomNumber, invitation.Room.Block,
+			invitation.ExpiresAt.UTC().Format("2 Jan 2006 15:04 MST"), link),
+	})
+}
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"mime"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxLoggedBody caps how much of a request body is read for debug logging.
+	maxLoggedBody = 64 << 10
+)
+
+// LogRequests attaches a request-scoped logger carrying the request id to the
+// request context and logs each request once it completes. At debug level the
+// JSON request body is logged too, with the fields in LOG_REDACT_FIELDS
+// masked.
+func LogRequests() gin.HandlerFunc {
+	redactor := logging.NewRedactor(logging.RedactFields())
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		logger := slog.Default().With(
+			"request_id", requestID,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		)
+		ctx := c.Request.Context()
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, logger))
+
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			if body, ok := readJSONBody(c); ok {
+				logger.Debug("request body", "body", redactor.JSON(body))
+			}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			attrs = append(attrs, "query", redactor.Query(query))
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			attrs = append(attrs, "errors", errs)
+		}
+
+		// Handlers may have added fields such as user_id to the logger.
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request completed", attrs...)
+	}
+}
+
+// logger returns the request-scoped logger for c.
+func logger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// addLogAttrs adds fields to every later log line for the request, including
+// the line written by LogRequests.
+func addLogAttrs(c *gin.Context, args ...any) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(logging.NewContext(ctx, logging.FromContext(ctx).With(args...)))
+}
+
+// readJSONBody reads a JSON request body and restores it for the handler.
+func readJSONBody(c *gin.Context) ([]byte, bool) {
+	if c.Request.Body == nil {
+		return nil, false
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/json" {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBody))
+	if err != nil {
+		return nil, false
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	return body, len(body) > 0
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID accepts caller-supplied ids that are short and printable so
+// they cannot be used to forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
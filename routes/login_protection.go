@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// loginPolicy controls brute-force protection on SignIn.
+type loginPolicy struct {
+	// MaxFailures consecutive failures lock the account for Lockout; zero or
+	// less never locks it.
+	MaxFailures int
+	Lockout     time.Duration
+	// IPMaxFailures failures from one address within IPWindow throttle that
+	// address regardless of which accounts it targets.
+	IPMaxFailures int
+	IPWindow      time.Duration
+	// After each failure the next attempt must wait DelayBase doubled per
+	// consecutive failure, capped at DelayMax.
+	DelayBase time.Duration
+	DelayMax  time.Duration
+}
+
+func currentLoginPolicy() loginPolicy {
+	return loginPolicy{
+		MaxFailures:   config.Int("LOGIN_MAX_FAILURES", 5),
+		Lockout:       config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		IPMaxFailures: config.Int("LOGIN_IP_MAX_FAILURES", 20),
+		IPWindow:      config.Duration("LOGIN_IP_WINDOW", 15*time.Minute),
+		DelayBase:     config.Duration("LOGIN_DELAY_BASE", time.Second),
+		DelayMax:      config.Duration("LOGIN_DELAY_MAX", 30*time.Second),
+	}
+}
+
+// delayAfter returns how long to wait after the given number of consecutive
+// failures.
+func (p loginPolicy) delayAfter(failures int) time.Duration {
+	if failures <= 0 || p.DelayBase <= 0 {
+		return 0
+	}
+	delay := float64(p.DelayBase) * math.Pow(2, float64(failures-1))
+	if delay > float64(p.DelayMax) {
+		return p.DelayMax
+	}
+	return time.Duration(delay)
+}
+
+// loginBlocked reports why the account may not sign in right now: "locked"
+// while it is locked out, "delayed" while it is inside its progressive delay,
+// or "" when it may. Callers answer blocked attempts exactly like a wrong
+// password so the response does not reveal that the account exists.
+func loginBlocked(user *models.User, policy loginPolicy) string {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return "locked"
+	}
+	if user.LastFailedLoginAt != nil &&
+		time.Now().Before(user.LastFailedLoginAt.Add(policy.delayAfter(user.FailedLoginCount))) {
+		return "delayed"
+	}
+	return ""
+}
+
+// ipFailureCount counts failed attempts from ip within the policy window.
+func ipFailureCount(ctx context.Context, db bun.IDB, ip string, window time.Duration) (int, error) {
+	return db.NewSelect().
+		Model((*models.LoginAttempt)(nil)).
+		Where("ip = ?", ip).
+		Where("success = false").
+		Where("created_at > ?", time.Now().Add(-window)).
+		Count(ctx)
+}
+
+func recordLoginAttempt(ctx context.Context, db bun.IDB, email string, userID *uuid.UUID, ip string, success bool) error {
+	attempt := &models.LoginAttempt{
+		Email:   email,
+		UserID:  userID,
+		IP:      ip,
+		Success: success,
+	}
+	_, err := db.NewInsert().Model(attempt).Exec(ctx)
+	return err
+}
+
+// recordFailedLogin bumps the user's failure counter and locks the account
+// once it reaches the policy limit; a limit of zero or less disables
+// lockouts. It reports whether this failure caused a lockout.
+func recordFailedLogin(ctx context.Context, db bun.IDB, user *models.User, ip string, policy loginPolicy) (bool, error) {
+	var result struct {
+		FailedLoginCount int        `bun:"failed_login_count"`
+		LockedUntil      *time.Time `bun:"locked_until"`
+	}
+
+	query := db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("last_failed_login_at = now()").
+		Where("id = ?", user.ID).
+		Returning("failed_login_count, locked_until")
+	if policy.MaxFailures > 0 {
+		// Locking resets the counter so progressive delays start over once
+		// the lockout expires.
+		query = query.
+			Set("failed_login_count = CASE WHEN failed_login_count + 1 >= ? THEN 0 ELSE failed_login_count + 1 END", policy.MaxFailures).
+			Set("locked_until = CASE WHEN failed_login_count + 1 >= ? THEN now() + ? * interval '1 second' ELSE locked_until END",
+				policy.MaxFailures, int(policy.Lockout.Seconds()))
+	} else {
+		query = query.Set("failed_login_count = failed_login_count + 1")
+	}
+	if err := query.Scan(ctx, &result); err != nil {
+		return false, err
+	}
+
+	// The counter only drops back to zero when this failure locked the account.
+	if policy.MaxFailures <= 0 || result.FailedLoginCount != 0 || result.LockedUntil == nil {
+		return false, nil
+	}
+
+	details := fmt.Sprintf("locked after %d failed sign-in attempts until %s", policy.MaxFailures, result.LockedUntil.UTC().Format(time.RFC3339))
+	return true, recordSecurityEvent(ctx, db, &models.SecurityEvent{
+		Type:    models.SecurityEventAccountLocked,
+		UserID:  &user.ID,
+		IP:      &ip,
+		Details: &details,
+	})
+}
+
+// resetFailedLogins clears the failure counter and any lockout after a
+// successful sign-in.
+func resetFailedLogins(ctx context.Context, db bun.IDB, userID uuid.UUID) error {
+	_, err := db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("failed_login_count = 0").
+		Set("last_failed_login_at = NULL").
+		Set("locked_until = NULL").
+		Where("id = ?", userID).
+		Exec(ctx)
+	return err
+}
+
+func recordSecurityEvent(ctx context.Context, db bun.IDB, event *models.SecurityEvent) error {
+	_, err := db.NewInsert().Model(event).Exec(ctx)
+	return err
+}
+
+// setRetryAfter sets the Retry-After header, rounding up to whole seconds.
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
@@ -0,0 +1,282 @@
+package routes
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	currentUserKey    = "currentUser"
+	currentSessionKey = "currentSession"
+	currentAPIKeyKey  = "currentAPIKey"
+)
+
+// RequireAuth validates the bearer access token on the request and stores the
+// authenticated user on the Gin context. Users whose role requires two-factor
+// authentication are turned away until they have enrolled. API keys are not
+// accepted; see RequireAuthOrAPIKey.
+func RequireAuth() gin.HandlerFunc {
+	return authenticate(authOptions{})
+}
+
+// RequireAuthForTwoFactorSetup is RequireAuth for the routes a user needs in
+// order to finish mandatory two-factor enrollment.
+func RequireAuthForTwoFactorSetup() gin.HandlerFunc {
+	return authenticate(authOptions{allowPendingTwoFactor: true})
+}
+
+// RequireAuthOrAPIKey is RequireAuth that also accepts an API key granted
+// scope. Session users implicitly hold every scope.
+func RequireAuthOrAPIKey(scope string) gin.HandlerFunc {
+	return authenticate(authOptions{apiKeyScope: scope})
+}
+
+type authOptions struct {
+	allowPendingTwoFactor bool
+	// apiKeyScope is the scope an API key needs for the route. API keys are
+	// rejected when it is empty.
+	apiKeyScope string
+}
+
+func authenticate(opts authOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential := bearerCredential(c)
+		if credential == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Missing bearer token",
+			})
+			return
+		}
+
+		if auth.IsAPIKey(credential) {
+			if opts.apiKeyScope == "" {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "API keys are not accepted for this endpoint",
+				})
+				return
+			}
+
+			user, key, ok := authenticateAPIKey(c, credential, opts.apiKeyScope)
+			if !ok {
+				return
+			}
+
+			c.Set(currentUserKey, user)
+			c.Set(currentAPIKeyKey, key)
+			addLogAttrs(c, "user_id", user.ID, "api_key_id", key.ID)
+			c.Next()
+			return
+		}
+
+		user, session, ok := authenticateSession(c, credential)
+		if !ok {
+			return
+		}
+
+		if !opts.allowPendingTwoFactor && twoFactorRequired(user.Role) && user.TOTPEnabledAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":                     "Two-factor authentication must be enabled for this account",
+				"two_factor_setup_required": true,
+			})
+			return
+		}
+
+		c.Set(currentUserKey, user)
+		c.Set(currentSessionKey, session)
+		addLogAttrs(c, "user_id", user.ID, "session_id", session.ID)
+		c.Next()
+	}
+}
+
+// bearerCredential extracts the credential from an "Authorization: Bearer"
+// or "Authorization: ApiKey" header.
+func bearerCredential(c *gin.Context) string {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	for _, scheme := range []string{"Bearer ", "ApiKey "} {
+		if credential, found := strings.CutPrefix(header, scheme); found {
+			return strings.TrimSpace(credential)
+		}
+	}
+	return ""
+}
+
+// authenticateSession resolves an access token to its user and live session.
+// It writes a 401 response and returns ok=false on failure.
+func authenticateSession(c *gin.Context, token string) (*models.User, *models.Session, bool) {
+	claims, err := auth.ParseAccessToken(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		return nil, nil, false
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		return nil, nil, false
+	}
+
+	sessionID, err := claims.Session()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		return nil, nil, false
+	}
+
+	ctx := c.Request.Context()
+	session := new(models.Session)
+	if err := database.DB.NewSelect().
+		Model(session).
+		Where("id = ?", sessionID).
+		Where("user_id = ?", userID).
+		Scan(ctx); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		return nil, nil, false
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Session has been revoked",
+		})
+		return nil, nil, false
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		if _, err := database.DB.NewUpdate().
+			Model(session).
+			Set("last_seen_at = now()").
+			Set("ip = ?", c.ClientIP()).
+			WherePK().
+			Exec(ctx); err != nil {
+			logger(c).Error("touch session failed", "session_id", session.ID, "error", err)
+		}
+	}
+
+	user := new(models.User)
+	if err := database.DB.NewSelect().
+		Model(user).
+		Where("id = ?", userID).
+		Scan(ctx); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		return nil, nil, false
+	}
+
+	if !user.Active() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Account has been deactivated",
+		})
+		return nil, nil, false
+	}
+
+	if user.PasswordChangedAt != nil && claims.IssuedAt < user.PasswordChangedAt.Unix() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Session has been revoked",
+		})
+		return nil, nil, false
+	}
+
+	return user, session, true
+}
+
+// authenticateAPIKey resolves an API key to its owner and checks it holds
+// scope. It writes a 401 or 403 response and returns ok=false on failure.
+func authenticateAPIKey(c *gin.Context, credential, scope string) (*models.User, *models.APIKey, bool) {
+	ctx := c.Request.Context()
+	key := new(models.APIKey)
+	if err := database.DB.NewSelect().
+		Model(key).
+		Relation("User").
+		Where("ak.key_hash = ?", auth.HashToken(credential)).
+		Scan(ctx); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid API key",
+		})
+		return nil, nil, false
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) || key.User == nil || !key.User.Active() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "API key has been revoked or has expired",
+		})
+		return nil, nil, false
+	}
+
+	if !key.HasScope(scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "API key is missing the " + scope + " scope",
+		})
+		return nil, nil, false
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > sessionTouchInterval {
+		if _, err := database.DB.NewUpdate().
+			Model(key).
+			Set("last_used_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			logger(c).Error("touch api key failed", "api_key_id", key.ID, "error", err)
+		}
+	}
+
+	return key.User, key, true
+}
+
+// currentUser returns the user stored by RequireAuth, or nil on routes that
+// are not behind the middleware.
+func currentUser(c *gin.Context) *models.User {
+	value, ok := c.Get(currentUserKey)
+	if !ok {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
+
+// currentSession returns the session stored by RequireAuth, or nil on routes
+// that are not behind the middleware and for API key requests.
+func currentSession(c *gin.Context) *models.Session {
+	value, ok := c.Get(currentSessionKey)
+	if !ok {
+		return nil
+	}
+	session, _ := value.(*models.Session)
+	return session
+}
+
+// RequireRole rejects requests from users whose role is not listed. It must
+// run after RequireAuth.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			return
+		}
+
+		if !slices.Contains(roles, user.Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "You do not have permission to perform this action",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
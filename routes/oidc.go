@@ -0,0 +1,277 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+	errOIDCEmailTaken     = errors.New("email belongs to an existing account")
+	errOIDCSignupDisabled = errors.New("self-signup is disabled")
+)
+
+var (
+	oidcOnce     sync.Once
+	oidcProvider *auth.OIDCProvider
+	oidcErr      error
+)
+
+// getOIDCProvider builds the provider from OIDC_* settings on first use.
+func getOIDCProvider() (*auth.OIDCProvider, error) {
+	oidcOnce.Do(func() {
+		cfg, err := auth.OIDCConfigFromEnv()
+		if err != nil {
+			oidcErr = err
+			return
+		}
+		oidcProvider = auth.NewOIDCProvider(cfg)
+	})
+	return oidcProvider, oidcErr
+}
+
+// OIDCLogin starts the authorization-code flow by redirecting the browser to
+// the identity provider. Pass ?redirect=false to receive the URL as JSON
+// instead, for clients that navigate themselves.
+func OIDCLogin(c *gin.Context) {
+	provider, err := getOIDCProvider()
+	if err != nil {
+		if !errors.Is(err, auth.ErrOIDCDisabled) {
+			logger(c).Error("oidc provider configuration invalid", "error", err)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not enabled"})
+		return
+	}
+
+	state, cookie, err := auth.NewOIDCState(oidcStateTTL)
+	if err != nil {
+		logger(c).Error("oidc state generation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		logger(c).Error("oidc authorization url failed", "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, int(oidcStateTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback finishes the authorization-code flow. On first login it
+// provisions a user and links the provider subject in identities; afterwards
+// it behaves like SignIn.
+func OIDCCallback(c *gin.Context) {
+	provider, err := getOIDCProvider()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not enabled"})
+		return
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Identity provider rejected the sign-in",
+			"details": providerErr,
+			"success": false,
+		})
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing sign-in state; start again"})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+
+	state, err := auth.ParseOIDCState(cookie)
+	if err != nil || state.State != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired sign-in state; start again"})
+		return
+	}
+
+	code := strings.TrimSpace(c.Query("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code query parameter is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	claims, err := provider.Exchange(ctx, code, state.Verifier, state.Nonce)
+	if err != nil {
+		logger(c).Warn("oidc code exchange failed", "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Failed to verify identity provider response",
+			"success": false,
+		})
+		return
+	}
+
+	var user *models.User
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		user, err = resolveOIDCUser(ctx, tx, provider.Config.Issuer, claims)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errOIDCSignupDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "No account exists for this identity. Ask your warden for an invitation.",
+				"success": false,
+			})
+			return
+		}
+		if errors.Is(err, errOIDCEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "An account with this email already exists. Sign in with your password to continue.",
+				"success": false,
+			})
+			return
+		}
+		logger(c).Error("oidc user provisioning failed", "subject", claims.Subject, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to sign in",
+			"success": false,
+		})
+		return
+	}
+
+	finishSignIn(c, user, user.Email, c.ClientIP())
+}
+
+// resolveOIDCUser returns the user linked to the provider subject, linking an
+// existing resident account with the same provider-verified email or
+// provisioning a new resident when there is none and self-signup is enabled.
+func resolveOIDCUser(ctx context.Context, tx bun.Tx, issuer string, claims *auth.IDTokenClaims) (*models.User, error) {
+	identity := new(models.Identity)
+	err := tx.NewSelect().
+		Model(identity).
+		Relation("User").
+		Where("idn.issuer = ?", issuer).
+		Where("idn.subject = ?", claims.Subject).
+		Scan(ctx)
+	if err == nil {
+		if _, err := tx.NewUpdate().
+			Model(identity).
+			Set("last_login_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return nil, err
+		}
+		return identity.User, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" {
+		return nil, errors.New("id_token has no email claim")
+	}
+
+	user := new(models.User)
+	err = tx.NewSelect().Model(user).Where("email = ?", email).Scan(ctx)
+	switch {
+	case err == nil:
+		// Only link by email when the provider vouches for it, otherwise
+		// anyone could claim an existing account. Staff and admin accounts
+		// are never linked this way: whoever controls the address at the
+		// provider would get their privileges without a password.
+		if !claims.EmailVerified || user.Role != models.RoleResident {
+			return nil, errOIDCEmailTaken
+		}
+		if user.EmailVerifiedAt == nil {
+			if _, err := tx.NewUpdate().
+				Model(user).
+				Set("email_verified_at = now()").
+				WherePK().
+				Returning("email_verified_at").
+				Exec(ctx); err != nil {
+				return nil, err
+			}
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if !selfSignupEnabled() {
+			return nil, errOIDCSignupDisabled
+		}
+		user, err = provisionOIDCUser(ctx, tx, email, claims)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &models.Identity{
+		UserID:      user.ID,
+		Issuer:      issuer,
+		Subject:     claims.Subject,
+		Email:       &email,
+		LastLoginAt: &now,
+	}
+	if _, err := tx.NewInsert().Model(link).Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionOIDCUser creates a resident account for a first-time SSO login.
+// The stored password is a hash of a random value, so the account can only
+// sign in through the provider until the user resets it.
+func provisionOIDCUser(ctx context.Context, tx bun.Tx, email string, claims *auth.IDTokenClaims) (*models.User, error) {
+	randomPassword, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = email
+	}
+
+	user := &models.User{
+		Name:     name,
+		Email:    email,
+		Password: hashedPassword,
+		Role:     models.RoleResident,
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if _, err := tx.NewInsert().Model(user).Returning("*").Exec(ctx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,294 @@
+package routes
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const mockOIDCClientID = "dbms-test"
+
+// mockOIDC is a local identity provider serving discovery, token and JWKS
+// endpoints. The token endpoint answers every code with an ID token carrying
+// claims, signed with key.
+type mockOIDC struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	claims        map[string]any
+	tokenRequests atomic.Int32
+}
+
+// newMockOIDC starts a mock provider and points the OIDC_* settings at it for
+// the duration of the test.
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockOIDC{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		m.tokenRequests.Add(1)
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("code") == "" || r.PostForm.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+		writeMockJSON(w, map[string]string{"id_token": m.idToken(t)})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	t.Setenv("OIDC_ISSUER", m.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", mockOIDCClientID)
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost/api/auth/oidc/callback")
+	resetOIDCProvider()
+	t.Cleanup(resetOIDCProvider)
+
+	return m
+}
+
+// resetOIDCProvider makes the next getOIDCProvider call re-read OIDC_*.
+func resetOIDCProvider() {
+	oidcOnce = sync.Once{}
+	oidcProvider = nil
+	oidcErr = nil
+}
+
+// baseClaims returns valid ID token claims for nonce.
+func (m *mockOIDC) baseClaims(nonce string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":   m.server.URL,
+		"sub":   uuid.NewString(),
+		"aud":   mockOIDCClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": nonce,
+	}
+}
+
+func (m *mockOIDC) idToken(t *testing.T) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, err := json.Marshal(m.claims)
+	if err != nil {
+		t.Errorf("marshal claims: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Errorf("sign id_token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeMockJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// callOIDCCallback runs OIDCCallback with the state cookie and query
+// parameters a browser would bring back from the provider.
+func callOIDCCallback(t *testing.T, cookie, state string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/callback", OIDCCallback)
+
+	query := url.Values{"state": {state}, "code": {"code"}}
+	req := httptest.NewRequest(http.MethodGet, "/callback?"+query.Encode(), nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	m := newMockOIDC(t)
+	state, cookie, err := auth.NewOIDCState(oidcStateTTL)
+	if err != nil {
+		t.Fatalf("new state: %v", err)
+	}
+	m.claims = m.baseClaims(state.Nonce)
+
+	rec := callOIDCCallback(t, cookie, "not-"+state.State)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	if n := m.tokenRequests.Load(); n != 0 {
+		t.Fatalf("token endpoint called %d times, want 0", n)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	m := newMockOIDC(t)
+	state, cookie, err := auth.NewOIDCState(oidcStateTTL)
+	if err != nil {
+		t.Fatalf("new state: %v", err)
+	}
+	m.claims = m.baseClaims("not-" + state.Nonce)
+
+	rec := callOIDCCallback(t, cookie, state.State)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusUnauthorized, rec.Body)
+	}
+	if n := m.tokenRequests.Load(); n != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", n)
+	}
+}
+
+// requireTestDB connects to the database in DB_* and migrates it, skipping
+// the test when DB_NAME is not set. Point DB_* at a scratch database.
+func requireTestDB(t *testing.T) {
+	t.Helper()
+	if os.Getenv("DB_NAME") == "" {
+		t.Skip("DB_NAME is not set; skipping database test")
+	}
+	if err := database.InitDB(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	if err := database.RunMigrations(context.Background()); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+}
+
+// insertOIDCTestUser creates an unverified user with role, deleted again when
+// the test ends.
+func insertOIDCTestUser(t *testing.T, role models.Role) *models.User {
+	t.Helper()
+	user := &models.User{
+		Name:     "OIDC Test",
+		Email:    "oidc-" + uuid.NewString() + "@example.com",
+		Password: "unused",
+		Role:     role,
+	}
+	if _, err := database.DB.NewInsert().Model(user).Returning("*").Exec(context.Background()); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	t.Cleanup(func() {
+		database.DB.NewDelete().Model(user).WherePK().Exec(context.Background())
+	})
+	return user
+}
+
+func TestOIDCCallbackLinksVerifiedEmail(t *testing.T) {
+	requireTestDB(t)
+	ctx := context.Background()
+	user := insertOIDCTestUser(t, models.RoleResident)
+
+	m := newMockOIDC(t)
+	state, cookie, err := auth.NewOIDCState(oidcStateTTL)
+	if err != nil {
+		t.Fatalf("new state: %v", err)
+	}
+	m.claims = m.baseClaims(state.Nonce)
+	m.claims["email"] = user.Email
+	m.claims["email_verified"] = true
+
+	rec := callOIDCCallback(t, cookie, state.State)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	identity := new(models.Identity)
+	if err := database.DB.NewSelect().
+		Model(identity).
+		Where("idn.issuer = ?", m.server.URL).
+		Where("idn.subject = ?", m.claims["sub"]).
+		Scan(ctx); err != nil {
+		t.Fatalf("load identity: %v", err)
+	}
+	if identity.UserID != user.ID {
+		t.Fatalf("identity linked to %s, want %s", identity.UserID, user.ID)
+	}
+
+	linked := new(models.User)
+	if err := database.DB.NewSelect().Model(linked).Where("id = ?", user.ID).Scan(ctx); err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if linked.EmailVerifiedAt == nil {
+		t.Fatal("email_verified_at not set after linking by verified email")
+	}
+}
+
+func TestOIDCCallbackRefusesToLinkPrivilegedAccounts(t *testing.T) {
+	requireTestDB(t)
+
+	for _, role := range []models.Role{models.RoleCleaner, models.RoleTechnician, models.RoleWarden, models.RoleAdmin} {
+		t.Run(string(role), func(t *testing.T) {
+			user := insertOIDCTestUser(t, role)
+
+			m := newMockOIDC(t)
+			state, cookie, err := auth.NewOIDCState(oidcStateTTL)
+			if err != nil {
+				t.Fatalf("new state: %v", err)
+			}
+			m.claims = m.baseClaims(state.Nonce)
+			m.claims["email"] = user.Email
+			m.claims["email_verified"] = true
+
+			rec := callOIDCCallback(t, cookie, state.State)
+
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusConflict, rec.Body)
+			}
+			linked, err := database.DB.NewSelect().
+				Model((*models.Identity)(nil)).
+				Where("idn.user_id = ?", user.ID).
+				Exists(context.Background())
+			if err != nil {
+				t.Fatalf("check identity: %v", err)
+			}
+			if linked {
+				t.Fatal("privileged account was linked by email")
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePagination reads the limit and offset query parameters. It writes a
+// 400 response and returns ok=false when either is malformed.
+func parsePagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit = defaultPageSize
+	if value := strings.TrimSpace(c.Query("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return 0, 0, false
+		}
+		limit = min(parsed, maxPageSize)
+	}
+
+	if value := strings.TrimSpace(c.Query("offset")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid offset",
+			})
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+
+	return limit, offset, true
+}
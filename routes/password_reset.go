@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/mailer"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+// ForgotPasswordRequest represents the forgot-password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the reset-password request body
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ForgotPassword emails a single-use reset link to the account. The response
+// is identical whether or not the email is registered so it cannot be used to
+// probe for accounts.
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user := new(models.User)
+	err := database.DB.NewSelect().
+		Model(user).
+		Where("email = ?", req.Email).
+		Where("deactivated_at IS NULL").
+		Scan(ctx)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger(c).Error("password reset user lookup failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	} else if err := sendPasswordReset(ctx, database.DB, user); err != nil {
+		logger(c).Error("send password reset failed", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "If an account exists for that email, a reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword and
+// signs the user out everywhere.
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		logger(c).Error("password hash failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		token := new(models.PasswordResetToken)
+		if err := tx.NewSelect().
+			Model(token).
+			Where("token_hash = ?", auth.HashToken(req.Token)).
+			Where("used = false").
+			Where("expires_at > now()").
+			For("UPDATE").
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidResetToken
+			}
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(token).
+			Set("used = true").
+			Set("used_at = now()").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model((*models.User)(nil)).
+			Set("password = ?", hashedPassword).
+			Set("password_changed_at = now()").
+			Where("id = ?", token.UserID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		return revokeUserSessions(ctx, tx, token.UserID)
+	})
+
+	if err != nil {
+		if errors.Is(err, errInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		logger(c).Error("password reset failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// sendPasswordReset invalidates any outstanding reset tokens for the user,
+// stores a fresh one and emails the link.
+func sendPasswordReset(ctx context.Context, db bun.IDB, user *models.User) error {
+	token, err := issuePasswordReset(ctx, db, user)
+	if err != nil {
+		return err
+	}
+	return mailPasswordReset(ctx, user, token)
+}
+
+// issuePasswordReset invalidates any outstanding reset tokens for the user
+// and stores a fresh one, returning it for mailPasswordReset.
+func issuePasswordReset(ctx context.Context, db bun.IDB, user *models.User) (string, error) {
+	if _, err := db.NewUpdate().
+		Model((*models.PasswordResetToken)(nil)).
+		Set("used = true").
+		Where("user_id = ?", user.ID).
+		Where("used = false").
+		Exec(ctx); err != nil {
+		return "", err
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(passwordResetTTL()),
+	}
+	if _, err := db.NewInsert().Model(record).Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// mailPasswordReset emails the user a link for a token from
+// issuePasswordReset.
+func mailPasswordReset(ctx context.Context, user *models.User, token string) error {
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.String("APP_BASE_URL", "http://localhost:3000"), url.QueryEscape(token))
+	return mailer.Default.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.",
+			user.Name, passwordResetTTL(), link),
+	})
+}
+
+func passwordResetTTL() time.Duration {
+	return config.Duration("PASSWORD_RESET_TTL", time.Hour)
+}
@@ -0,0 +1,481 @@
+package routes
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	attachmentVariantOriginal  = "original"
+	attachmentVariantThumbnail = "thumbnail"
+
+	// multipartOverhead leaves room for form boundaries and headers on top of
+	// the file itself.
+	multipartOverhead = 64 << 10
+	maxFilenameLength = 255
+)
+
+// attachmentPolicy limits what may be uploaded to a request.
+type attachmentPolicy struct {
+	MaxBytes       int64
+	MaxPerRequest  int
+	AllowedTypes   []string
+	MaxImagePixels int
+	ThumbnailSize  int
+}
+
+func currentAttachmentPolicy() attachmentPolicy {
+	return attachmentPolicy{
+		MaxBytes:       int64(config.Int("ATTACHMENT_MAX_BYTES", 10<<20)),
+		MaxPerRequest:  config.Int("ATTACHMENT_MAX_PER_REQUEST", 20),
+		AllowedTypes:   config.List("ATTACHMENT_ALLOWED_TYPES", []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}),
+		MaxImagePixels: config.Int("ATTACHMENT_MAX_IMAGE_PIXELS", 40_000_000),
+		ThumbnailSize:  config.Int("ATTACHMENT_THUMBNAIL_SIZE", 320),
+	}
+}
+
+// ListRequestAttachments returns the files attached to a request with signed
+// download links that expire after ATTACHMENT_URL_TTL.
+func ListRequestAttachments(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	var attachments []models.RequestAttachment
+	if err := database.DB.NewSelect().
+		Model(&attachments).
+		Relation("Uploader").
+		Where("att.request_id = ?", requestID).
+		Order("att.created_at ASC", "att.id ASC").
+		Scan(c.Request.Context()); err != nil {
+		logger(c).Error("list request attachments failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list attachments",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	for i := range attachments {
+		if err := signAttachmentURLs(user, &attachments[i]); err != nil {
+			logger(c).Error("sign attachment url failed", "attachment_id", attachments[i].ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to list attachments",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": attachments})
+}
+
+// UploadRequestAttachment stores a multipart "file" upload against a request.
+// The content type is sniffed from the bytes rather than trusted from the
+// client, and images get a JPEG thumbnail.
+func UploadRequestAttachment(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Checked before the body is read so callers cannot make the server
+	// buffer uploads for requests they cannot see.
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	policy := currentAttachmentPolicy()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, policy.MaxBytes+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			rejectAttachmentTooLarge(c, policy)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A file is required in the \"file\" form field",
+		})
+		return
+	}
+	if header.Size > policy.MaxBytes {
+		rejectAttachmentTooLarge(c, policy)
+		return
+	}
+
+	ctx := c.Request.Context()
+	count, err := database.DB.NewSelect().
+		Model((*models.RequestAttachment)(nil)).
+		Where("request_id = ?", requestID).
+		Count(ctx)
+	if err != nil {
+		logger(c).Error("count request attachments failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to upload attachment",
+		})
+		return
+	}
+	if count >= policy.MaxPerRequest {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "This request already has the maximum of " + strconv.Itoa(policy.MaxPerRequest) + " attachments",
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		logger(c).Error("open uploaded file failed", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read uploaded file",
+		})
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !slices.Contains(policy.AllowedTypes, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":         "Files of type " + contentType + " are not accepted",
+			"allowed_types": policy.AllowedTypes,
+		})
+		return
+	}
+
+	var thumbnail []byte
+	if isThumbnailable(contentType) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			logger(c).Error("rewind uploaded file failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to upload attachment",
+			})
+			return
+		}
+		thumbnail, err = makeThumbnail(file, policy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid image",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		logger(c).Error("rewind uploaded file failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to upload attachment",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	attachment := &models.RequestAttachment{
+		RequestID:   requestID,
+		UploaderID:  &user.ID,
+		Filename:    attachmentFilename(header.Filename),
+		ContentType: contentType,
+		SizeBytes:   header.Size,
+		StorageKey:  fmt.Sprintf("requests/%d/%s", requestID, uuid.NewString()),
+	}
+
+	if err := storage.Default.Put(ctx, attachment.StorageKey, file); err != nil {
+		logger(c).Error("store attachment failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to upload attachment",
+		})
+		return
+	}
+	if thumbnail != nil {
+		key := attachment.StorageKey + "-thumb.jpg"
+		if err := storage.Default.Put(ctx, key, bytes.NewReader(thumbnail)); err != nil {
+			logger(c).Error("store attachment thumbnail failed", "request_id", requestID, "error", err)
+			deleteAttachmentBlobs(c, attachment)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to upload attachment",
+			})
+			return
+		}
+		attachment.ThumbnailKey = &key
+	}
+
+	if _, err := database.DB.NewInsert().Model(attachment).Returning("*").Exec(ctx); err != nil {
+		logger(c).Error("create request attachment failed", "request_id", requestID, "error", err)
+		deleteAttachmentBlobs(c, attachment)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to upload attachment",
+		})
+		return
+	}
+	attachment.Uploader = user
+
+	if err := signAttachmentURLs(user, attachment); err != nil {
+		logger(c).Error("sign attachment url failed", "attachment_id", attachment.ID, "error", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Attachment uploaded",
+		"attachment": attachment,
+	})
+}
+
+// DeleteRequestAttachment removes an attachment and its blobs. Uploaders may
+// delete their own files; wardens and admins may delete any.
+func DeleteRequestAttachment(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.ParseInt(c.Param("attachmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attachment id",
+		})
+		return
+	}
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	attachment := new(models.RequestAttachment)
+	if err := database.DB.NewSelect().
+		Model(attachment).
+		Where("att.id = ?", attachmentID).
+		Where("att.request_id = ?", requestID).
+		Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attachment not found",
+			})
+			return
+		}
+		logger(c).Error("load request attachment failed", "attachment_id", attachmentID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete attachment",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	moderator := user.Role == models.RoleWarden || user.Role == models.RoleAdmin
+	if !moderator && (attachment.UploaderID == nil || *attachment.UploaderID != user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only delete your own attachments",
+		})
+		return
+	}
+
+	if _, err := database.DB.NewDelete().Model(attachment).WherePK().Exec(ctx); err != nil {
+		logger(c).Error("delete request attachment failed", "attachment_id", attachmentID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete attachment",
+		})
+		return
+	}
+	deleteAttachmentBlobs(c, attachment)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attachment deleted",
+	})
+}
+
+// DownloadRequestAttachment serves an attachment to anyone holding a valid
+// signed link from ListRequestAttachments or UploadRequestAttachment. The link
+// is the authorization, so it works in <img> tags and plain browser
+// downloads.
+func DownloadRequestAttachment(c *gin.Context) {
+	claims, err := auth.ParseDownloadToken(c.Query("token"))
+	if err != nil {
+		message := "Invalid download link"
+		if errors.Is(err, auth.ErrExpiredToken) {
+			message = "Download link has expired"
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return
+	}
+
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || strconv.FormatInt(claims.Attachment, 10) != c.Param("attachmentId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid download link"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	attachment := new(models.RequestAttachment)
+	if err := database.DB.NewSelect().
+		Model(attachment).
+		Where("att.id = ?", claims.Attachment).
+		Where("att.request_id = ?", requestID).
+		Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		logger(c).Error("load request attachment failed", "attachment_id", claims.Attachment, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download attachment"})
+		return
+	}
+
+	key, contentType, size := attachment.StorageKey, attachment.ContentType, attachment.SizeBytes
+	filename := attachment.Filename
+	if claims.Variant == attachmentVariantThumbnail {
+		if attachment.ThumbnailKey == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment has no thumbnail"})
+			return
+		}
+		key, contentType, size = *attachment.ThumbnailKey, "image/jpeg", -1
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + "-thumbnail.jpg"
+	}
+
+	blob, err := storage.Default.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		logger(c).Error("open attachment blob failed", "attachment_id", attachment.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download attachment"})
+		return
+	}
+	defer blob.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+	maxAge := max(0, time.Until(time.Unix(claims.ExpiresAt, 0)).Seconds())
+
+	c.DataFromReader(http.StatusOK, size, contentType, blob, map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": filename}),
+		"Content-Security-Policy": "default-src 'none'; sandbox",
+		"X-Content-Type-Options":  "nosniff",
+		"Cache-Control":           fmt.Sprintf("private, max-age=%d", int(maxAge)),
+	})
+}
+
+// signAttachmentURLs fills in signed download links for attachment issued to
+// user.
+func signAttachmentURLs(user *models.User, attachment *models.RequestAttachment) error {
+	base := fmt.Sprintf("/api/requests/%d/attachments/%d/download?token=", attachment.RequestID, attachment.ID)
+
+	token, expiresAt, err := auth.IssueDownloadToken(user.ID.String(), attachment.ID, attachmentVariantOriginal)
+	if err != nil {
+		return err
+	}
+	attachment.URL = base + token
+	attachment.URLExpiresAt = &expiresAt
+
+	if attachment.ThumbnailKey != nil {
+		token, _, err := auth.IssueDownloadToken(user.ID.String(), attachment.ID, attachmentVariantThumbnail)
+		if err != nil {
+			return err
+		}
+		attachment.ThumbnailURL = base + token
+	}
+	return nil
+}
+
+// deleteAttachmentBlobs removes the stored bytes of attachment, logging
+// rather than failing since the row is already gone or was never written.
+func deleteAttachmentBlobs(c *gin.Context, attachment *models.RequestAttachment) {
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailKey != nil {
+		keys = append(keys, *attachment.ThumbnailKey)
+	}
+	for _, key := range keys {
+		if err := storage.Default.Delete(c.Request.Context(), key); err != nil {
+			logger(c).Error("delete attachment blob failed", "key", key, "error", err)
+		}
+	}
+}
+
+// isThumbnailable reports whether the standard library can decode images of
+// contentType.
+func isThumbnailable(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// makeThumbnail decodes an image and returns a JPEG thumbnail of it. Images
+// whose dimensions exceed the pixel limit are rejected before decoding so a
+// small file cannot expand into a huge bitmap.
+func makeThumbnail(r io.ReadSeeker, policy attachmentPolicy) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > policy.MaxImagePixels {
+		return nil, fmt.Errorf("image dimensions %dx%d exceed the limit", cfg.Width, cfg.Height)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, storage.Thumbnail(img, policy.ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func rejectAttachmentTooLarge(c *gin.Context, policy attachmentPolicy) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":     "File is too large",
+		"max_bytes": policy.MaxBytes,
+	})
+}
+
+// attachmentFilename reduces a client-supplied filename to its base name.
+func attachmentFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
@@ -0,0 +1,349 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+const maxCommentLength = 4000
+
+type createCommentInput struct {
+	Body     string `json:"body" binding:"required"`
+	Internal bool   `json:"internal"`
+}
+
+type updateCommentInput struct {
+	Body string `json:"body" binding:"required"`
+}
+
+var (
+	errCommentNotFound    = errors.New("comment not found")
+	errCommentForbidden   = errors.New("user may not change this comment")
+	errCommentWindowEnded = errors.New("comment can no longer be changed")
+)
+
+// commentEditWindow is how long authors may edit their comments.
+func commentEditWindow() time.Duration {
+	return config.Duration("COMMENT_EDIT_WINDOW", 15*time.Minute)
+}
+
+// commentDeleteWindow is how long authors may delete their comments. Wardens
+// and admins may delete any comment at any time.
+func commentDeleteWindow() time.Duration {
+	return config.Duration("COMMENT_DELETE_WINDOW", time.Hour)
+}
+
+// seesInternalComments reports whether user may read and write staff notes.
+func seesInternalComments(user *models.User) bool {
+	return user.Role != models.RoleResident
+}
+
+// ListRequestComments returns the comment thread of a request, oldest first.
+// Residents only see public comments.
+func ListRequestComments(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	user := currentUser(c)
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	var comments []models.RequestComment
+	query := database.DB.NewSelect().
+		Model(&comments).
+		Relation("Author").
+		Where("com.request_id = ?", requestID).
+		Where("com.deleted_at IS NULL").
+		Order("com.created_at ASC", "com.id ASC").
+		Limit(limit).
+		Offset(offset)
+	if !seesInternalComments(user) {
+		query = query.Where("NOT com.internal")
+	}
+
+	total, err := query.ScanAndCount(ctx)
+	if err != nil {
+		logger(c).Error("list request comments failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list comments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments": comments,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// CreateRequestComment posts a comment on a request. Staff and admins may mark
+// it internal to hide it from residents.
+func CreateRequestComment(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input createCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	body, ok := commentBody(c, input.Body)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	if input.Internal && !seesInternalComments(user) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only staff can post internal notes",
+		})
+		return
+	}
+
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	comment := &models.RequestComment{
+		RequestID: requestID,
+		AuthorID:  &user.ID,
+		Body:      body,
+		Internal:  input.Internal,
+	}
+	if _, err := database.DB.NewInsert().Model(comment).Returning("*").Exec(c.Request.Context()); err != nil {
+		logger(c).Error("create request comment failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to post comment",
+		})
+		return
+	}
+	comment.Author = user
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Comment posted",
+		"comment": comment,
+	})
+}
+
+// UpdateRequestComment lets the author rewrite a comment within the edit
+// window.
+func UpdateRequestComment(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid comment id",
+		})
+		return
+	}
+
+	var input updateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	body, ok := commentBody(c, input.Body)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	comment := new(models.RequestComment)
+	ctx := c.Request.Context()
+
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockComment(ctx, tx, user, requestID, commentID, comment); err != nil {
+			return err
+		}
+		if comment.AuthorID == nil || *comment.AuthorID != user.ID {
+			return errCommentForbidden
+		}
+		if time.Since(comment.CreatedAt) > commentEditWindow() {
+			return errCommentWindowEnded
+		}
+
+		_, err := tx.NewUpdate().
+			Model(comment).
+			Set("body = ?", body).
+			Set("edited_at = now()").
+			WherePK().
+			Returning("body, edited_at").
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		writeCommentError(c, err, "Failed to update comment")
+		return
+	}
+	comment.Author = user
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated",
+		"comment": comment,
+	})
+}
+
+// DeleteRequestComment removes a comment. Authors may delete their own within
+// the delete window; wardens and admins may delete any comment they can see.
+func DeleteRequestComment(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid comment id",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	comment := new(models.RequestComment)
+	ctx := c.Request.Context()
+
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockComment(ctx, tx, user, requestID, commentID, comment); err != nil {
+			return err
+		}
+
+		moderator := user.Role == models.RoleWarden || user.Role == models.RoleAdmin
+		if !moderator {
+			if comment.AuthorID == nil || *comment.AuthorID != user.ID {
+				return errCommentForbidden
+			}
+			if time.Since(comment.CreatedAt) > commentDeleteWindow() {
+				return errCommentWindowEnded
+			}
+		}
+
+		_, err := tx.NewUpdate().
+			Model(comment).
+			Set("deleted_at = now()").
+			WherePK().
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		writeCommentError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment deleted",
+	})
+}
+
+// lockComment loads a live comment on a request visible to user, locking the
+// row. Internal comments are invisible to residents.
+func lockComment(ctx context.Context, tx bun.Tx, user *models.User, requestID int, commentID int64, comment *models.RequestComment) error {
+	visible, err := scopeRequests(tx.NewSelect().
+		Model((*models.Request)(nil)).
+		Where("req.id = ?", requestID), user).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return sql.ErrNoRows
+	}
+
+	query := tx.NewSelect().
+		Model(comment).
+		Where("com.id = ?", commentID).
+		Where("com.request_id = ?", requestID).
+		Where("com.deleted_at IS NULL").
+		For("UPDATE")
+	if !seesInternalComments(user) {
+		query = query.Where("NOT com.internal")
+	}
+	if err := query.Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errCommentNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func writeCommentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
+		})
+	case errors.Is(err, errCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Comment not found",
+		})
+	case errors.Is(err, errCommentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only change your own comments",
+		})
+	case errors.Is(err, errCommentWindowEnded):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "This comment can no longer be changed",
+		})
+	default:
+		logger(c).Error("request comment change failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
+
+// commentBody trims and validates a comment body, writing a 400 response and
+// returning ok=false when it is empty or too long.
+func commentBody(c *gin.Context, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "body must not be empty",
+		})
+		return "", false
+	}
+	if len([]rune(body)) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "body must be at most " + strconv.Itoa(maxCommentLength) + " characters",
+		})
+		return "", false
+	}
+	return body, true
+}
@@ -0,0 +1,242 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/notify"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+const maxFeedbackLength = 2000
+
+type confirmRequestInput struct {
+	Rating   int     `json:"rating" binding:"required,min=1,max=5"`
+	Feedback *string `json:"feedback"`
+}
+
+type disputeRequestInput struct {
+	Feedback string `json:"feedback" binding:"required"`
+}
+
+// GetRequestConfirmation returns the confirmation for the latest completion
+// of a request, or null when it was never completed.
+func GetRequestConfirmation(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	confirmation, err := workflow.LatestConfirmation(c.Request.Context(), database.DB, requestID, false)
+	if err != nil {
+		if errors.Is(err, workflow.ErrNoConfirmation) {
+			c.JSON(http.StatusOK, gin.H{"confirmation": nil})
+			return
+		}
+		logger(c).Error("load request confirmation failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load confirmation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"confirmation": confirmation})
+}
+
+// ConfirmRequest lets a member of the room accept a completed request with a
+// 1-5 rating and optional feedback.
+func ConfirmRequest(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input confirmRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	feedback, ok := feedbackText(c, input.Feedback)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	var confirmation *models.RequestConfirmation
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockRequest(ctx, tx, user, requestID, request); err != nil {
+			return err
+		}
+
+		var err error
+		confirmation, err = workflow.Confirm(ctx, tx, request, user, input.Rating, feedback)
+		return err
+	})
+
+	if err != nil {
+		writeConfirmationError(c, err, request, "Failed to confirm request")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Request confirmed",
+		"confirmation": confirmation,
+	})
+}
+
+// DisputeRequest lets a member of the room reject a completed request within
+// the dispute window, saying what is wrong. The request is reopened and its
+// assignee and the block's wardens are told.
+func DisputeRequest(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input disputeRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	feedback, ok := feedbackText(c, &input.Feedback)
+	if !ok {
+		return
+	}
+	if feedback == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "feedback must say what is wrong",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	var confirmation *models.RequestConfirmation
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockRequest(ctx, tx, user, requestID, request); err != nil {
+			return err
+		}
+
+		var err error
+		confirmation, err = workflow.Dispute(ctx, tx, request, user, feedback)
+		return err
+	})
+
+	if err != nil {
+		writeConfirmationError(c, err, request, "Failed to dispute request")
+		return
+	}
+
+	if err := notify.Disputed(ctx, database.DB, request, confirmation); err != nil {
+		logger(c).Error("dispute notification failed", "request_id", requestID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Request reopened",
+		"request":      request,
+		"confirmation": confirmation,
+	})
+}
+
+// askForConfirmation emails the room members of a request that was just
+// completed. Failures are logged; the status change has already been saved.
+func askForConfirmation(c *gin.Context, request *models.Request) {
+	ctx := c.Request.Context()
+	confirmation, err := workflow.LatestConfirmation(ctx, database.DB, request.ID, false)
+	if err == nil {
+		err = notify.CompletionConfirmation(ctx, database.DB, request, confirmation)
+	}
+	if err != nil {
+		logger(c).Error("confirmation request failed", "request_id", request.ID, "error", err)
+	}
+}
+
+// lockRequest loads a request visible to user with its room, locking the row.
+func lockRequest(ctx context.Context, tx bun.Tx, user *models.User, requestID int, request *models.Request) error {
+	query := tx.NewSelect().
+		Model(request).
+		Relation("Room").
+		Where("req.id = ?", requestID).
+		For("UPDATE OF req")
+	return scopeRequests(query, user).Scan(ctx)
+}
+
+func writeConfirmationError(c *gin.Context, err error, request *models.Request, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
+		})
+	case errors.Is(err, workflow.ErrNoConfirmation):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Only completed requests can be confirmed or disputed",
+			"current_status": request.Status,
+		})
+	case errors.Is(err, workflow.ErrConfirmationAnswered):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "This completion has already been answered",
+		})
+	case errors.Is(err, workflow.ErrDisputeWindowEnded):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The dispute window for this request has ended",
+		})
+	case errors.Is(err, workflow.ErrOpenRequestExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The room already has another open request of this type",
+		})
+	case errors.Is(err, workflow.ErrTransitionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Your role cannot reopen this request",
+		})
+	case errors.Is(err, workflow.ErrStaleStatus):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The request changed status; reload it and try again",
+		})
+	default:
+		logger(c).Error("request confirmation failed", "request_id", request.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
+
+// feedbackText trims optional feedback, treating blank text as absent, and
+// writes a 400 response returning ok=false when it is too long.
+func feedbackText(c *gin.Context, feedback *string) (*string, bool) {
+	if feedback == nil {
+		return nil, true
+	}
+	trimmed := strings.TrimSpace(*feedback)
+	if trimmed == "" {
+		return nil, true
+	}
+	if len([]rune(trimmed)) > maxFeedbackLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "feedback must be at most " + strconv.Itoa(maxFeedbackLength) + " characters",
+		})
+		return nil, false
+	}
+	return &trimmed, true
+}
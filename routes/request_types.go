@@ -0,0 +1,248 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+var requestTypeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+type CreateRequestTypeRequest struct {
+	Name                    string `json:"name" binding:"required"`
+	Label                   string `json:"label" binding:"required"`
+	Category                string `json:"category" binding:"required"`
+	DefaultPriority         string `json:"default_priority"`
+	SLAResolveWithinMinutes *int   `json:"sla_resolve_within_minutes" binding:"omitempty,min=1"`
+	SLAWarnBeforeMinutes    int    `json:"sla_warn_before_minutes" binding:"min=0"`
+	OneActivePerRoom        *bool  `json:"one_active_per_room"`
+	Active                  *bool  `json:"active"`
+}
+
+// UpdateRequestTypeRequest changes a request type. Setting
+// sla_resolve_within_minutes to 0 removes the type's default SLA.
+type UpdateRequestTypeRequest struct {
+	Label                   *string `json:"label"`
+	Category                *string `json:"category"`
+	DefaultPriority         *string `json:"default_priority"`
+	SLAResolveWithinMinutes *int    `json:"sla_resolve_within_minutes" binding:"omitempty,min=0"`
+	SLAWarnBeforeMinutes    *int    `json:"sla_warn_before_minutes" binding:"omitempty,min=0"`
+	OneActivePerRoom        *bool   `json:"one_active_per_room"`
+	Active                  *bool   `json:"active"`
+}
+
+var errRequestTypeExists = errors.New("request type already exists")
+
+// ListRequestTypes returns the request types that can be filed, by label.
+// Admins may pass include_inactive=true to see retired types as well.
+func ListRequestTypes(c *gin.Context) {
+	var types []models.RequestTypeConfig
+	query := database.DB.NewSelect().
+		Model(&types).
+		Order("rtype.label ASC")
+	if c.Query("include_inactive") != "true" || currentUser(c).Role != models.RoleAdmin {
+		query = query.Where("rtype.active")
+	}
+	if err := query.Scan(c.Request.Context()); err != nil {
+		logger(c).Error("list request types failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list request types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"request_types": types})
+}
+
+// CreateRequestType adds a kind of request residents can file. A type
+// allows only one open request per room unless one_active_per_room is false.
+func CreateRequestType(c *gin.Context) {
+	var req CreateRequestTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := workflow.NormalizeRequestType(req.Name)
+	if !requestTypeNamePattern.MatchString(string(name)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be lowercase letters, digits and underscores, starting with a letter, at most 32 characters"})
+		return
+	}
+
+	config := &models.RequestTypeConfig{
+		Name:                    name,
+		Label:                   strings.TrimSpace(req.Label),
+		Category:                models.RequestCategory(strings.ToLower(strings.TrimSpace(req.Category))),
+		DefaultPriority:         models.RequestPriorityNormal,
+		SLAResolveWithinMinutes: req.SLAResolveWithinMinutes,
+		SLAWarnBeforeMinutes:    req.SLAWarnBeforeMinutes,
+		OneActivePerRoom:        req.OneActivePerRoom == nil || *req.OneActivePerRoom,
+		Active:                  req.Active == nil || *req.Active,
+	}
+	if value := strings.TrimSpace(req.DefaultPriority); value != "" {
+		config.DefaultPriority = models.RequestPriority(strings.ToLower(value))
+	}
+	if message := validateRequestType(config); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		exists, err := tx.NewSelect().
+			Model((*models.RequestTypeConfig)(nil)).
+			Where("rtype.name = ?", name).
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errRequestTypeExists
+		}
+		_, err = tx.NewInsert().Model(config).Returning("*").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errRequestTypeExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A request type with this name already exists"})
+			return
+		}
+		logger(c).Error("create request type failed", "name", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request type"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Request type created",
+		"request_type": config,
+	})
+}
+
+// UpdateRequestType changes a request type. Its name cannot change. Setting
+// active to false retires it: nothing new can be filed under it, but
+// existing requests keep it. Changing one_active_per_room only affects
+// requests filed afterwards.
+func UpdateRequestType(c *gin.Context) {
+	name := workflow.NormalizeRequestType(c.Param("name"))
+
+	var req UpdateRequestTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	config := new(models.RequestTypeConfig)
+	var message string
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().
+			Model(config).
+			Where("rtype.name = ?", name).
+			For("UPDATE").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		if req.Label != nil {
+			config.Label = strings.TrimSpace(*req.Label)
+		}
+		if req.Category != nil {
+			config.Category = models.RequestCategory(strings.ToLower(strings.TrimSpace(*req.Category)))
+		}
+		if req.DefaultPriority != nil {
+			config.DefaultPriority = models.RequestPriority(strings.ToLower(strings.TrimSpace(*req.DefaultPriority)))
+		}
+		if req.SLAResolveWithinMinutes != nil {
+			if *req.SLAResolveWithinMinutes == 0 {
+				config.SLAResolveWithinMinutes = nil
+			} else {
+				config.SLAResolveWithinMinutes = req.SLAResolveWithinMinutes
+			}
+		}
+		if req.SLAWarnBeforeMinutes != nil {
+			config.SLAWarnBeforeMinutes = *req.SLAWarnBeforeMinutes
+		}
+		if req.OneActivePerRoom != nil {
+			config.OneActivePerRoom = *req.OneActivePerRoom
+		}
+		if req.Active != nil {
+			config.Active = *req.Active
+		}
+		if message = validateRequestType(config); message != "" {
+			return nil
+		}
+
+		_, err := tx.NewUpdate().
+			Model(config).
+			Column("label", "category", "default_priority", "sla_resolve_within_minutes",
+				"sla_warn_before_minutes", "one_active_per_room", "active").
+			WherePK().
+			Returning("*").
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Request type not found"})
+			return
+		}
+		logger(c).Error("update request type failed", "name", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request type"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Request type updated",
+		"request_type": config,
+	})
+}
+
+// validateRequestType returns why config cannot be saved, or "" when it can.
+func validateRequestType(config *models.RequestTypeConfig) string {
+	switch {
+	case config.Label == "":
+		return "label must not be empty"
+	case !config.Category.Valid():
+		return "category must be cleaning or maintenance"
+	case !config.DefaultPriority.Valid():
+		return "Unsupported default_priority"
+	case config.SLAResolveWithinMinutes != nil && config.SLAWarnBeforeMinutes >= *config.SLAResolveWithinMinutes:
+		return "sla_warn_before_minutes must be less than sla_resolve_within_minutes"
+	}
+	return ""
+}
+
+// knownRequestType checks requestType against request_types, retired types
+// included, writing a 400 response and returning false when there is no such
+// type.
+func knownRequestType(c *gin.Context, requestType models.RequestType) bool {
+	if _, err := workflow.LookupRequestType(c.Request.Context(), database.DB, requestType, true); err != nil {
+		if errors.Is(err, workflow.ErrUnknownRequestType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported request type"})
+			return false
+		}
+		logger(c).Error("look up request type failed", "type", requestType, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up request type"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/notify"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+type setTimeWindowsInput struct {
+	TimeWindows []timeWindowInput `json:"time_windows" binding:"dive"`
+}
+
+// SetRequestTimeWindows replaces the preferred visit windows of an open
+// request. Send an empty list to clear them. Only the reporter, wardens and
+// admins may change them.
+func SetRequestTimeWindows(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input setTimeWindowsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Relation("Room").
+			Where("req.id = ?", requestID).
+			For("UPDATE OF req")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+		if !canEditRequest(user, request) {
+			return errRequestEditForbidden
+		}
+
+		windows := timeWindowModels(input.TimeWindows)
+		if err := workflow.ValidateTimeWindows(ctx, tx, request.Room.Block, windows); err != nil {
+			return err
+		}
+		return workflow.SetTimeWindows(ctx, tx, request, user, windows)
+	})
+
+	var windowErr *workflow.WindowError
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, errRequestEditForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Only the reporter, wardens and admins can edit this request",
+			})
+		case errors.As(err, &windowErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid time window",
+				"details": windowErr.Error(),
+			})
+		case errors.Is(err, workflow.ErrRequestClosed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Closed requests cannot be edited",
+				"current_status": request.Status,
+			})
+		default:
+			logger(c).Error("set request time windows failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update time windows",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Time windows updated",
+		"time_windows": request.TimeWindows,
+	})
+}
+
+type recordNoAccessInput struct {
+	Note *string `json:"note"`
+}
+
+// RecordNoAccess logs that staff visited the room of an open request but could
+// not get in. The reporter is emailed and asked to add preferred visit times.
+func RecordNoAccess(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input recordNoAccessInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if input.Note != nil {
+		trimmed := strings.TrimSpace(*input.Note)
+		if trimmed == "" {
+			input.Note = nil
+		} else {
+			input.Note = &trimmed
+		}
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	var attempt *models.RequestAccessAttempt
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Relation("Room").
+			Where("req.id = ?", requestID).
+			For("UPDATE OF req")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		attempt, err = workflow.RecordNoAccess(ctx, tx, request, user, input.Note)
+		return err
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, workflow.ErrRequestClosed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Request is closed",
+				"current_status": request.Status,
+			})
+		default:
+			logger(c).Error("record no access failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to record attempt",
+			})
+		}
+		return
+	}
+
+	if err := notify.NoAccess(ctx, database.DB, request, attempt); err != nil {
+		logger(c).Error("no access notification failed", "request_id", requestID, "error", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "No-access attempt recorded",
+		"attempt": attempt,
+	})
+}
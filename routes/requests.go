@@ -7,9 +7,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/adii2ma/dbms-backend/database"
 	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/notify"
+	"github.com/adii2ma/dbms-backend/workflow"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
@@ -18,16 +21,35 @@ import (
 type createRequestInput struct {
 	Type        string  `json:"type" binding:"required"`
 	Description *string `json:"description"`
-	UserID      string  `json:"user_id"`
 	RoomID      *int    `json:"room_id"`
 	RoomNumber  string  `json:"room_number"`
 	Block       string  `json:"block"`
+	Priority    string  `json:"priority"`
+	// TimeWindows are when the reporter would like staff to visit.
+	TimeWindows []timeWindowInput `json:"time_windows" binding:"omitempty,dive"`
+}
+
+type timeWindowInput struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+}
+
+func (in timeWindowInput) model() *models.RequestTimeWindow {
+	return &models.RequestTimeWindow{StartsAt: in.StartsAt, EndsAt: in.EndsAt}
+}
+
+func timeWindowModels(inputs []timeWindowInput) []*models.RequestTimeWindow {
+	windows := make([]*models.RequestTimeWindow, len(inputs))
+	for i, input := range inputs {
+		windows[i] = input.model()
+	}
+	return windows
 }
 
-var errActiveRequestExists = errors.New("active request already exists for this room and type")
 var errRoomBlockMismatch = errors.New("room does not belong to provided block")
+var errRoomForbidden = errors.New("user may not access this room")
 
-// CreateRequest handles creation of a new cleaning or maintenance request.
+// CreateRequest files a new request of any active request type.
 func CreateRequest(c *gin.Context) {
 	var input createRequestInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -38,13 +60,7 @@ func CreateRequest(c *gin.Context) {
 		return
 	}
 
-	requestType := models.RequestType(strings.ToLower(strings.TrimSpace(input.Type)))
-	if requestType != models.RequestTypeCleaning && requestType != models.RequestTypeMaintenance {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Unsupported request type",
-		})
-		return
-	}
+	requestType := workflow.NormalizeRequestType(input.Type)
 
 	if input.Description != nil {
 		trimmed := strings.TrimSpace(*input.Description)
@@ -55,50 +71,35 @@ func CreateRequest(c *gin.Context) {
 		}
 	}
 
-	ctx := c.Request.Context()
-
-	var userID *uuid.UUID
-	roomNumber := strings.TrimSpace(input.RoomNumber)
-	block := strings.TrimSpace(input.Block)
-
-	if input.UserID != "" {
-		parsed, err := uuid.Parse(input.UserID)
-		if err != nil {
+	// Left empty, the request type's default priority applies.
+	var priority models.RequestPriority
+	if value := strings.TrimSpace(input.Priority); value != "" {
+		priority = models.RequestPriority(strings.ToLower(value))
+		if !priority.Valid() {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid user_id",
+				"error": "Unsupported priority",
 			})
 			return
 		}
+	}
 
-		var user models.User
-		if err := database.DB.NewSelect().Model(&user).Where("id = ?", parsed).Scan(ctx); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error": "User not found",
-				})
-				return
-			}
-
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to look up user",
-			})
-			return
-		}
+	ctx := c.Request.Context()
 
-		userID = &parsed
+	user := currentUser(c)
+	roomNumber := strings.TrimSpace(input.RoomNumber)
+	block := strings.TrimSpace(input.Block)
 
-		if roomNumber == "" && user.RoomName != nil {
-			cleaned := strings.TrimSpace(*user.RoomName)
-			if cleaned != "" {
-				roomNumber = cleaned
-			}
+	if roomNumber == "" && user.RoomName != nil {
+		cleaned := strings.TrimSpace(*user.RoomName)
+		if cleaned != "" {
+			roomNumber = cleaned
 		}
+	}
 
-		if block == "" && user.Block != nil {
-			trimmed := strings.TrimSpace(*user.Block)
-			if trimmed != "" {
-				block = trimmed
-			}
+	if block == "" && user.Block != nil {
+		trimmed := strings.TrimSpace(*user.Block)
+		if trimmed != "" {
+			block = trimmed
 		}
 	}
 
@@ -123,100 +124,86 @@ func CreateRequest(c *gin.Context) {
 			if block != "" && !strings.EqualFold(room.Block, block) {
 				return errRoomBlockMismatch
 			}
+			block = room.Block
 		} else {
-			err := tx.NewSelect().
-				Model(&room).
-				Where("room_number = ?", roomNumber).
-				Where("block = ?", block).
-				Scan(ctx)
+			found, err := findOrCreateRoom(ctx, tx, block, roomNumber)
 			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					room = models.Room{RoomNumber: roomNumber, Block: block}
-					if _, err := tx.NewInsert().Model(&room).Exec(ctx); err != nil {
-						return err
-					}
-				} else {
-					return err
-				}
+				return err
 			}
+			room = *found
 			roomID = room.ID
 			block = room.Block
 		}
 
-		if userID != nil {
-			exists, err := tx.NewSelect().
-				Model((*models.RoomMember)(nil)).
-				Where("room_id = ?", roomID).
-				Where("block = ?", block).
-				Where("user_id = ?", *userID).
-				Exists(ctx)
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				member := &models.RoomMember{RoomID: roomID, Block: block, UserID: *userID}
-				if _, err := tx.NewInsert().Model(member).Exec(ctx); err != nil {
-					return err
-				}
-			}
-		}
-
-		exists, err := tx.NewSelect().
-			Model((*models.Request)(nil)).
-			Where("room_id = ?", roomID).
-			Where("type = ?", requestType).
-			Where("status = ?", models.RequestStatusActive).
-			Exists(ctx)
+		allowed, err := canAccessRoom(ctx, tx, user, &room)
 		if err != nil {
 			return err
 		}
 
-		if exists {
-			return errActiveRequestExists
-		}
-
-		request := &models.Request{
-			RoomID: roomID,
-			Type:   requestType,
-			Status: models.RequestStatusActive,
-		}
-
-		if input.Description != nil {
-			request.Description = input.Description
-		}
+		if !allowed {
+			// Residents may still file for the room they registered with;
+			// doing so links them into room_members.
+			if user.Role != models.RoleResident || !isProfileRoom(user, &room) {
+				return errRoomForbidden
+			}
 
-		if userID != nil {
-			request.UserID = userID
+			if err := ensureRoomMember(ctx, tx, &room, user.ID); err != nil {
+				return err
+			}
 		}
 
-		if _, err := tx.NewInsert().Model(request).Exec(ctx); err != nil {
+		windows := timeWindowModels(input.TimeWindows)
+		if err := workflow.ValidateTimeWindows(ctx, tx, block, windows); err != nil {
 			return err
 		}
 
-		if err := tx.NewSelect().Model(request).WherePK().Scan(ctx); err != nil {
+		request := &models.Request{
+			UserID:            &user.ID,
+			RoomID:            roomID,
+			Type:              requestType,
+			Description:       input.Description,
+			SuggestedPriority: priority,
+			TimeWindows:       windows,
+		}
+		if err := workflow.Create(ctx, tx, request, user); err != nil {
 			return err
 		}
 
+		request.Room = &room
 		createdRequest = request
 		return nil
 	})
 
+	var windowErr *workflow.WindowError
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "Room not found",
 			})
-		case errors.Is(err, errActiveRequestExists):
+		case errors.Is(err, workflow.ErrUnknownRequestType):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported request type",
+			})
+		case errors.As(err, &windowErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid time window",
+				"details": windowErr.Error(),
+			})
+		case errors.Is(err, workflow.ErrOpenRequestExists):
 			c.JSON(http.StatusConflict, gin.H{
-				"error": "An active request already exists for this room and type",
+				"error": "An open request already exists for this room and type",
 			})
 		case errors.Is(err, errRoomBlockMismatch):
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "room does not belong to provided block",
 			})
+		case errors.Is(err, errRoomForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "You cannot file requests for this room",
+			})
 		default:
+			logger(c).Error("create request failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to create request",
 			})
@@ -224,24 +211,25 @@ func CreateRequest(c *gin.Context) {
 		return
 	}
 
+	if createdRequest.Priority == models.RequestPriorityEmergency {
+		alertEmergency(c, createdRequest)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Request created successfully",
 		"request": createdRequest,
 	})
 }
 
-// GetActiveRequest resolves the active request for a room/type combination.
+// GetActiveRequest resolves the open request for a room/type combination,
+// whatever stage of the workflow it is in. Types that allow several open
+// requests per room return the newest.
 func GetActiveRequest(c *gin.Context) {
-	typeParam := strings.TrimSpace(c.Query("type"))
-	requestType := models.RequestType(strings.ToLower(typeParam))
+	requestType := workflow.NormalizeRequestType(c.Query("type"))
 	if requestType == "" {
 		requestType = models.RequestTypeCleaning
 	}
-
-	if requestType != models.RequestTypeCleaning && requestType != models.RequestTypeMaintenance {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Unsupported request type",
-		})
+	if !knownRequestType(c, requestType) {
 		return
 	}
 
@@ -272,7 +260,7 @@ func GetActiveRequest(c *gin.Context) {
 		if err := database.DB.NewSelect().
 			Model(&room).
 			Where("room_number = ?", roomNumber).
-			Where("block = ?", blockParam).
+			Where("lower(block) = lower(?)", blockParam).
 			Scan(ctx); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				c.JSON(http.StatusOK, gin.H{"request": nil})
@@ -293,14 +281,20 @@ func GetActiveRequest(c *gin.Context) {
 	}
 
 	request := new(models.Request)
-	if err := database.DB.NewSelect().
+	query := database.DB.NewSelect().
 		Model(request).
 		Relation("Room").
 		Relation("User").
-		Where("room_id = ?", roomID).
-		Where("type = ?", requestType).
-		Where("status = ?", models.RequestStatusActive).
-		Scan(ctx); err != nil {
+		Relation("Assignee").
+		Relation("TimeWindows", orderTimeWindows).
+		Relation("NoAccessAttempts", orderAccessAttempts).
+		Where("req.room_id = ?", roomID).
+		Where("req.type = ?", requestType).
+		Where("req.status IN (?)", bun.In(models.OpenRequestStatuses)).
+		Order("req.created_at DESC", "req.id DESC").
+		Limit(1)
+
+	if err := scopeRequests(query, currentUser(c)).Scan(ctx); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusOK, gin.H{"request": nil})
 			return
@@ -315,20 +309,15 @@ func GetActiveRequest(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"request": request})
 }
 
-// GetRequestStatus fetches the most recent request and returns its current status.
+// GetRequestStatus fetches the most recent request and returns its detailed
+// workflow status, whether it is still open and the statuses the caller may
+// move it to.
 func GetRequestStatus(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	typeParam := strings.TrimSpace(c.Query("type"))
-	var requestType models.RequestType
-	if typeParam != "" {
-		requestType = models.RequestType(strings.ToLower(typeParam))
-		if requestType != models.RequestTypeCleaning && requestType != models.RequestTypeMaintenance {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Unsupported request type",
-			})
-			return
-		}
+	requestType := workflow.NormalizeRequestType(c.Query("type"))
+	if requestType != "" && !knownRequestType(c, requestType) {
+		return
 	}
 
 	roomIDParam := strings.TrimSpace(c.Query("room_id"))
@@ -358,7 +347,7 @@ func GetRequestStatus(c *gin.Context) {
 		if err := database.DB.NewSelect().
 			Model(&room).
 			Where("room_number = ?", roomNumber).
-			Where("block = ?", blockParam).
+			Where("lower(block) = lower(?)", blockParam).
 			Scan(ctx); err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				c.JSON(http.StatusOK, gin.H{
@@ -386,15 +375,16 @@ func GetRequestStatus(c *gin.Context) {
 		Model(request).
 		Relation("Room").
 		Relation("User").
-		Where("room_id = ?", roomID).
-		Order("updated_at DESC").
+		Relation("Assignee").
+		Where("req.room_id = ?", roomID).
+		Order("req.updated_at DESC").
 		Limit(1)
 
 	if requestType != "" {
-		query = query.Where("type = ?", requestType)
+		query = query.Where("req.type = ?", requestType)
 	}
 
-	if err := query.Scan(ctx); err != nil {
+	if err := scopeRequests(query, currentUser(c)).Scan(ctx); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusOK, gin.H{
 				"status":  "none",
@@ -410,7 +400,610 @@ func GetRequestStatus(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status":  request.Status,
+		"status":        request.Status,
+		"open":          request.Status.IsOpen(),
+		"next_statuses": workflow.NextStatuses(currentUser(c).Role, request.Status),
+		"request":       request,
+	})
+}
+
+// ListRequests returns the requests visible to the signed-in user, open
+// emergencies first and then newest first. Residents see their rooms, staff
+// and wardens their block and admins everything.
+func ListRequests(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var requests []models.Request
+	query := database.DB.NewSelect().
+		Model(&requests).
+		Relation("Room").
+		Relation("User").
+		Relation("Assignee").
+		Relation("TimeWindows", orderTimeWindows).
+		Relation("NoAccessAttempts", orderAccessAttempts).
+		OrderExpr("req.priority = ? DESC", models.RequestPriorityEmergency).
+		Order("req.created_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if requestType := workflow.NormalizeRequestType(c.Query("type")); requestType != "" {
+		if !knownRequestType(c, requestType) {
+			return
+		}
+		query = query.Where("req.type = ?", requestType)
+	}
+
+	if categoryParam := strings.TrimSpace(c.Query("category")); categoryParam != "" {
+		category := models.RequestCategory(strings.ToLower(categoryParam))
+		if !category.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported category",
+			})
+			return
+		}
+		query = query.Where("req.type IN (SELECT name FROM request_types WHERE category = ?)", category)
+	}
+
+	if statusParam := strings.TrimSpace(c.Query("status")); statusParam != "" {
+		status := models.RequestStatus(strings.ToLower(statusParam))
+		switch {
+		case status == "open":
+			query = query.Where("req.status IN (?)", bun.In(models.OpenRequestStatuses))
+		case status.Valid():
+			query = query.Where("req.status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported status",
+			})
+			return
+		}
+	}
+
+	if priorityParam := strings.TrimSpace(c.Query("priority")); priorityParam != "" {
+		priority := models.RequestPriority(strings.ToLower(priorityParam))
+		if !priority.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Unsupported priority",
+			})
+			return
+		}
+		query = query.Where("req.priority = ?", priority)
+	}
+
+	switch models.SLAState(strings.ToLower(strings.TrimSpace(c.Query("sla")))) {
+	case "":
+	case models.SLAStateBreached:
+		query = query.Where("req.sla_breached_at IS NOT NULL")
+	case models.SLAStateAtRisk:
+		query = query.
+			Where("req.sla_at_risk_at IS NOT NULL").
+			Where("req.sla_breached_at IS NULL").
+			Where("req.status IN (?)", bun.In(models.OpenRequestStatuses))
+	case models.SLAStateOK:
+		query = query.
+			Where("req.sla_breached_at IS NULL").
+			Where("(req.sla_at_risk_at IS NULL OR req.status NOT IN (?))", bun.In(models.OpenRequestStatuses))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported sla filter",
+		})
+		return
+	}
+
+	if assigneeParam := strings.TrimSpace(c.Query("assignee_id")); assigneeParam != "" {
+		if assigneeParam == "me" {
+			query = query.Where("req.assignee_id = ?", currentUser(c).ID)
+		} else {
+			assigneeID, err := uuid.Parse(assigneeParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid assignee_id",
+				})
+				return
+			}
+			query = query.Where("req.assignee_id = ?", assigneeID)
+		}
+	}
+
+	if roomIDParam := strings.TrimSpace(c.Query("room_id")); roomIDParam != "" {
+		roomID, err := strconv.Atoi(roomIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid room_id",
+			})
+			return
+		}
+		query = query.Where("req.room_id = ?", roomID)
+	}
+
+	if blockParam := strings.TrimSpace(c.Query("block")); blockParam != "" {
+		query = query.Where("req.room_id IN (SELECT id FROM rooms WHERE lower(block) = lower(?))", blockParam)
+	}
+
+	total, err := scopeRequests(query, currentUser(c)).ScanAndCount(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list requests",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+type updateRequestStatusInput struct {
+	Status string `json:"status" binding:"required"`
+}
+
+var errRequestWorkForbidden = errors.New("user may not work this request")
+
+// UpdateRequestStatus moves a request to a new status. Allowed moves and the
+// roles that may make them come from workflow.Transitions; anything else is a
+// 409. Cleaners and technicians may only move requests of their category that
+// are unassigned or assigned to them.
+func UpdateRequestStatus(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input updateRequestStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	status := models.RequestStatus(strings.ToLower(strings.TrimSpace(input.Status)))
+
+	user := currentUser(c)
+	request := new(models.Request)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Relation("Room").
+			Relation("TypeConfig").
+			Where("req.id = ?", requestID).
+			For("UPDATE OF req")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+
+		if user.Role == models.RoleCleaner || user.Role == models.RoleTechnician {
+			if !canWorkRequest(user, request) || (request.AssigneeID != nil && *request.AssigneeID != user.ID) {
+				return errRequestWorkForbidden
+			}
+		}
+
+		return workflow.SetStatus(ctx, tx, request, user, status)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, workflow.ErrTransitionForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Your role cannot move this request to " + string(status),
+			})
+		case errors.Is(err, errRequestWorkForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This request is not yours to work on",
+			})
+		case errors.Is(err, workflow.ErrAssigneeRequired):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Use POST /api/requests/:id/assign to assign a request",
+			})
+		case errors.Is(err, workflow.ErrInvalidTransition), errors.Is(err, workflow.ErrStaleStatus):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Cannot move request from " + string(request.Status) + " to " + string(status),
+				"current_status": request.Status,
+				"next_statuses":  workflow.NextStatuses(user.Role, request.Status),
+			})
+		default:
+			logger(c).Error("update request status failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update request status",
+			})
+		}
+		return
+	}
+
+	if request.Status == models.RequestStatusCompleted {
+		askForConfirmation(c, request)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Request status updated",
+		"request":       request,
+		"next_statuses": workflow.NextStatuses(user.Role, request.Status),
+	})
+}
+
+type updateRequestInput struct {
+	Description *string `json:"description"`
+}
+
+// UpdateRequest edits the description of an open request. Only the resident
+// who filed it, wardens and admins may edit; the change is recorded in the
+// request's timeline.
+func UpdateRequest(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input updateRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if input.Description != nil {
+		trimmed := strings.TrimSpace(*input.Description)
+		if trimmed == "" {
+			input.Description = nil
+		} else {
+			input.Description = &trimmed
+		}
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Where("req.id = ?", requestID).
+			For("UPDATE")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+		if !canEditRequest(user, request) {
+			return errRequestEditForbidden
+		}
+
+		return workflow.SetDescription(ctx, tx, request, user, input.Description)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, errRequestEditForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Only the reporter, wardens and admins can edit this request",
+			})
+		case errors.Is(err, workflow.ErrRequestClosed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Closed requests cannot be edited",
+				"current_status": request.Status,
+			})
+		default:
+			logger(c).Error("update request failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update request",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Request updated",
+		"request": request,
+	})
+}
+
+var errRequestEditForbidden = errors.New("user may not edit this request")
+
+// canEditRequest reports whether user may change the details of request.
+func canEditRequest(user *models.User, request *models.Request) bool {
+	switch user.Role {
+	case models.RoleWarden, models.RoleAdmin:
+		return true
+	default:
+		return request.UserID != nil && *request.UserID == user.ID
+	}
+}
+
+// GetRequestTimeline returns the history of a request, oldest entry first:
+// its creation and every status change, assignment and edit since, with the
+// user who made each change.
+func GetRequestTimeline(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+	if !requireVisibleRequest(c, requestID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var events []models.RequestEvent
+	if err := database.DB.NewSelect().
+		Model(&events).
+		Relation("Actor").
+		Where("rev.request_id = ?", requestID).
+		Order("rev.created_at ASC", "rev.id ASC").
+		Scan(ctx); err != nil {
+		logger(c).Error("list request events failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve request timeline",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"request_id": requestID,
+		"events":     events,
+	})
+}
+
+type setRequestPriorityInput struct {
+	Priority string `json:"priority" binding:"required"`
+}
+
+// SetRequestPriority lets staff confirm or change the priority a reporter
+// suggested. Raising a request to emergency alerts the block straight away.
+func SetRequestPriority(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input setRequestPriorityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+	priority := models.RequestPriority(strings.ToLower(strings.TrimSpace(input.Priority)))
+	if !priority.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported priority",
+		})
+		return
+	}
+
+	user := currentUser(c)
+	request := new(models.Request)
+	var escalated bool
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Relation("Room").
+			Where("req.id = ?", requestID).
+			For("UPDATE OF req")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+
+		escalated = request.Priority != models.RequestPriorityEmergency && priority == models.RequestPriorityEmergency
+		return workflow.SetPriority(ctx, tx, request, user, priority)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, workflow.ErrRequestClosed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Closed requests cannot be reprioritised",
+				"current_status": request.Status,
+			})
+		default:
+			logger(c).Error("set request priority failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update priority",
+			})
+		}
+		return
+	}
+
+	if escalated {
+		alertEmergency(c, request)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Priority updated",
+		"request": request,
+	})
+}
+
+// alertEmergency pages the block about an emergency request. It runs after
+// the change has committed; a failed alert is logged rather than undoing it.
+func alertEmergency(c *gin.Context, request *models.Request) {
+	if err := notify.Emergency(c.Request.Context(), database.DB, request); err != nil {
+		logger(c).Error("emergency alert failed", "request_id", request.ID, "error", err)
+	}
+}
+
+type assignRequestInput struct {
+	AssigneeID *uuid.UUID `json:"assignee_id"`
+}
+
+var errAssigneeIneligible = errors.New("assignee cannot work this request")
+
+// AssignRequest gives a request to a staff member. Cleaners and technicians
+// can only take requests themselves; wardens and admins can assign anyone
+// who works the room's block. Omit assignee_id to assign yourself.
+func AssignRequest(c *gin.Context) {
+	requestID, ok := requestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input assignRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	assigneeID := user.ID
+	if input.AssigneeID != nil {
+		assigneeID = *input.AssigneeID
+	}
+	if assigneeID != user.ID && user.Role != models.RoleWarden && user.Role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only wardens and admins can assign requests to someone else",
+		})
+		return
+	}
+
+	request := new(models.Request)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(request).
+			Relation("Room").
+			Relation("TypeConfig").
+			Where("req.id = ?", requestID).
+			For("UPDATE OF req")
+		if err := scopeRequests(query, user).Scan(ctx); err != nil {
+			return err
+		}
+
+		assignee := user
+		if assigneeID != user.ID {
+			assignee = new(models.User)
+			if err := tx.NewSelect().Model(assignee).Where("id = ?", assigneeID).Scan(ctx); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return errAssigneeIneligible
+				}
+				return err
+			}
+		}
+		if !canWorkRequest(assignee, request) {
+			return errAssigneeIneligible
+		}
+
+		return workflow.Assign(ctx, tx, request, user, assignee)
+	})
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Request not found",
+			})
+		case errors.Is(err, errAssigneeIneligible):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Assignee must be an active staff member for this block who handles " + string(request.Type) + " requests",
+			})
+		case errors.Is(err, workflow.ErrTransitionForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Your role cannot assign requests",
+			})
+		case errors.Is(err, workflow.ErrNotAssignable), errors.Is(err, workflow.ErrStaleStatus):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Request is closed and cannot be assigned",
+				"current_status": request.Status,
+			})
+		default:
+			logger(c).Error("assign request failed", "request_id", requestID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to assign request",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Request assigned",
 		"request": request,
 	})
 }
+
+// canWorkRequest reports whether user may be assigned request: an active
+// warden of the room's block, or an active cleaner or technician of that block
+// for request types in the cleaning or maintenance category respectively.
+func canWorkRequest(user *models.User, request *models.Request) bool {
+	if !user.Active() || request.Room == nil || request.TypeConfig == nil || user.Block == nil ||
+		!strings.EqualFold(strings.TrimSpace(*user.Block), request.Room.Block) {
+		return false
+	}
+
+	switch user.Role {
+	case models.RoleWarden:
+		return true
+	case models.RoleCleaner, models.RoleTechnician:
+		return request.TypeConfig.Category.StaffRole() == user.Role
+	default:
+		return false
+	}
+}
+
+// requestIDParam parses the :id path parameter, writing a 400 response and
+// returning ok=false when it is not a number.
+func requestIDParam(c *gin.Context) (int, bool) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request id",
+		})
+		return 0, false
+	}
+	return requestID, true
+}
+
+// requireVisibleRequest writes a 404 response and returns false unless the
+// signed-in user may see the request.
+func requireVisibleRequest(c *gin.Context, requestID int) bool {
+	query := database.DB.NewSelect().
+		Model((*models.Request)(nil)).
+		Where("req.id = ?", requestID)
+	exists, err := scopeRequests(query, currentUser(c)).Exists(c.Request.Context())
+	if err != nil {
+		logger(c).Error("request lookup failed", "request_id", requestID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to look up request",
+		})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
+		})
+		return false
+	}
+	return true
+}
+
+func orderTimeWindows(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Order("rtw.starts_at ASC")
+}
+
+func orderAccessAttempts(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Order("raa.attempted_at ASC")
+}
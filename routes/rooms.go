@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// findOrCreateRoom looks up a room by block and room number, creating it when
+// it does not exist yet.
+func findOrCreateRoom(ctx context.Context, db bun.IDB, block, roomNumber string) (*models.Room, error) {
+	room := new(models.Room)
+	err := db.NewSelect().
+		Model(room).
+		Where("room_number = ?", roomNumber).
+		Where("block = ?", block).
+		Scan(ctx)
+	if err == nil {
+		return room, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	room = &models.Room{
+		Block:      block,
+		RoomNumber: roomNumber,
+	}
+	if _, err := db.NewInsert().Model(room).Returning("id").Exec(ctx); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// ensureRoomMember links the user to the room unless they are already a
+// member, reactivating an inactive membership.
+func ensureRoomMember(ctx context.Context, db bun.IDB, room *models.Room, userID uuid.UUID) error {
+	member := &models.RoomMember{
+		RoomID: room.ID,
+		Block:  room.Block,
+		UserID: userID,
+	}
+	_, err := db.NewInsert().
+		Model(member).
+		On("CONFLICT (room_id, block, user_id) DO UPDATE").
+		Set("active = true").
+		Exec(ctx)
+	return err
+}
+
+// setRoomMembershipsActive flags every room membership of the user active or
+// inactive without removing the rows.
+func setRoomMembershipsActive(ctx context.Context, db bun.IDB, userID uuid.UUID, active bool) error {
+	_, err := db.NewUpdate().
+		Model((*models.RoomMember)(nil)).
+		Set("active = ?", active).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
+
+// linkProfileRoom makes the user a member of the block and room on their
+// profile, creating the room when needed. Users without a full room on their
+// profile are left unlinked.
+func linkProfileRoom(ctx context.Context, db bun.IDB, user *models.User) error {
+	if user.Block == nil || user.RoomName == nil || *user.Block == "" || *user.RoomName == "" {
+		return nil
+	}
+
+	room, err := findOrCreateRoom(ctx, db, *user.Block, *user.RoomName)
+	if err != nil {
+		return err
+	}
+	return ensureRoomMember(ctx, db, room, user.ID)
+}
+
+// unlinkProfileRoom removes the user's membership of the block and room on
+// their profile, if any.
+func unlinkProfileRoom(ctx context.Context, db bun.IDB, user *models.User) error {
+	if user.Block == nil || user.RoomName == nil || *user.Block == "" || *user.RoomName == "" {
+		return nil
+	}
+
+	_, err := db.NewDelete().
+		Model((*models.RoomMember)(nil)).
+		Where("user_id = ?", user.ID).
+		Where("block = ?", *user.Block).
+		Where("room_id IN (SELECT id FROM rooms WHERE block = ? AND room_number = ?)", *user.Block, *user.RoomName).
+		Exec(ctx)
+	return err
+}
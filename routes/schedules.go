@@ -0,0 +1,459 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+// schedulePreviewCount is how many upcoming occurrences GetSchedule lists.
+const schedulePreviewCount = 5
+
+type createScheduleInput struct {
+	RoomID      int     `json:"room_id" binding:"required"`
+	Type        string  `json:"type" binding:"required"`
+	Recurrence  string  `json:"recurrence" binding:"required"`
+	StartsOn    string  `json:"starts_on"`
+	WindowStart string  `json:"window_start" binding:"required"`
+	WindowEnd   string  `json:"window_end" binding:"required"`
+	Description *string `json:"description"`
+	Priority    string  `json:"priority"`
+}
+
+type updateScheduleInput struct {
+	Recurrence  *string `json:"recurrence"`
+	StartsOn    *string `json:"starts_on"`
+	WindowStart *string `json:"window_start"`
+	WindowEnd   *string `json:"window_end"`
+	Description *string `json:"description"`
+	Priority    *string `json:"priority"`
+	Active      *bool   `json:"active"`
+}
+
+var (
+	errScheduleForbidden = errors.New("user may not manage this schedule")
+	errScheduleEnded     = errors.New("recurrence has no upcoming occurrences")
+)
+
+// scheduleError is a validation failure reported back to the client as a 400.
+type scheduleError struct {
+	message string
+}
+
+func (e *scheduleError) Error() string {
+	return e.message
+}
+
+// CreateSchedule sets up a recurring request for a room. The scheduler files
+// a request for each occurrence ahead of its visit window.
+func CreateSchedule(c *gin.Context) {
+	var input createScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	schedule := &models.RequestSchedule{
+		RoomID:      input.RoomID,
+		Type:        workflow.NormalizeRequestType(input.Type),
+		Description: trimOptional(input.Description),
+		Recurrence:  strings.ToUpper(strings.TrimSpace(input.Recurrence)),
+		WindowStart: strings.TrimSpace(input.WindowStart),
+		WindowEnd:   strings.TrimSpace(input.WindowEnd),
+		Active:      true,
+		CreatedBy:   &user.ID,
+	}
+	if err := applyScheduleDates(schedule, input.StartsOn, input.Priority); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		config, err := workflow.LookupRequestType(ctx, tx, schedule.Type, false)
+		if err != nil {
+			return err
+		}
+		if schedule.Priority == "" {
+			schedule.Priority = config.DefaultPriority
+		}
+
+		room := new(models.Room)
+		if err := tx.NewSelect().Model(room).Where("id = ?", schedule.RoomID).Scan(ctx); err != nil {
+			return err
+		}
+		allowed, err := canAccessRoom(ctx, tx, user, room)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errRoomForbidden
+		}
+		schedule.Room = room
+
+		if err := planSchedule(ctx, tx, schedule); err != nil {
+			return err
+		}
+		_, err = tx.NewInsert().Model(schedule).Returning("*").Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		writeScheduleError(c, err, "Room not found", "Failed to create schedule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Schedule created",
+		"schedule": schedule,
+	})
+}
+
+// ListSchedules returns the schedules for rooms the signed-in user can see.
+func ListSchedules(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var schedules []models.RequestSchedule
+	query := database.DB.NewSelect().
+		Model(&schedules).
+		Relation("Room").
+		Order("rs.next_run_at ASC NULLS LAST", "rs.id ASC").
+		Limit(limit).
+		Offset(offset)
+
+	if roomIDParam := strings.TrimSpace(c.Query("room_id")); roomIDParam != "" {
+		roomID, err := strconv.Atoi(roomIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid room_id",
+			})
+			return
+		}
+		query = query.Where("rs.room_id = ?", roomID)
+	}
+
+	switch c.Query("active") {
+	case "":
+	case "true":
+		query = query.Where("rs.active")
+	case "false":
+		query = query.Where("NOT rs.active")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "active must be true or false",
+		})
+		return
+	}
+
+	total, err := scopeRooms(query, currentUser(c), "rs.room_id").ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list schedules failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list schedules",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// GetSchedule returns a schedule with the visit windows of its next few
+// occurrences.
+func GetSchedule(c *gin.Context) {
+	scheduleID, ok := scheduleIDParam(c)
+	if !ok {
+		return
+	}
+
+	schedule := new(models.RequestSchedule)
+	query := database.DB.NewSelect().
+		Model(schedule).
+		Relation("Room").
+		Where("rs.id = ?", scheduleID)
+	if err := scopeRooms(query, currentUser(c), "rs.room_id").Scan(c.Request.Context()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Schedule not found",
+			})
+			return
+		}
+		logger(c).Error("load schedule failed", "schedule_id", scheduleID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load schedule",
+		})
+		return
+	}
+
+	upcoming := []*models.RequestTimeWindow{}
+	if rule, err := workflow.ScheduleRule(schedule); err == nil && schedule.Active {
+		for _, date := range rule.Occurrences(time.Now().In(workflow.ServiceLocation()), schedulePreviewCount) {
+			if window, err := workflow.ScheduleWindow(schedule, date); err == nil {
+				upcoming = append(upcoming, window)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedule":             schedule,
+		"upcoming_occurrences": upcoming,
+	})
+}
+
+// UpdateSchedule changes a schedule's recurrence, window, details or pauses
+// and resumes it. Only its creator, wardens and admins may change it.
+func UpdateSchedule(c *gin.Context) {
+	scheduleID, ok := scheduleIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input updateScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	schedule := new(models.RequestSchedule)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockSchedule(ctx, tx, user, scheduleID, schedule); err != nil {
+			return err
+		}
+
+		if input.Recurrence != nil {
+			schedule.Recurrence = strings.ToUpper(strings.TrimSpace(*input.Recurrence))
+		}
+		if input.WindowStart != nil {
+			schedule.WindowStart = strings.TrimSpace(*input.WindowStart)
+		}
+		if input.WindowEnd != nil {
+			schedule.WindowEnd = strings.TrimSpace(*input.WindowEnd)
+		}
+		if input.Description != nil {
+			schedule.Description = trimOptional(input.Description)
+		}
+		if input.Active != nil {
+			schedule.Active = *input.Active
+		}
+		startsOn := ""
+		if input.StartsOn != nil {
+			startsOn = *input.StartsOn
+		}
+		priority := ""
+		if input.Priority != nil {
+			priority = *input.Priority
+		}
+		if err := applyScheduleDates(schedule, startsOn, priority); err != nil {
+			return err
+		}
+
+		if schedule.Active {
+			if err := planSchedule(ctx, tx, schedule); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.NewUpdate().
+			Model(schedule).
+			Column("recurrence", "starts_on", "window_start", "window_end", "description", "priority", "active", "next_run_at").
+			WherePK().
+			Returning("*").
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		writeScheduleError(c, err, "Schedule not found", "Failed to update schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Schedule updated",
+		"schedule": schedule,
+	})
+}
+
+// DeleteSchedule removes a schedule. Requests it already filed are kept.
+func DeleteSchedule(c *gin.Context) {
+	scheduleID, ok := scheduleIDParam(c)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	schedule := new(models.RequestSchedule)
+	ctx := c.Request.Context()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := lockSchedule(ctx, tx, user, scheduleID, schedule); err != nil {
+			return err
+		}
+		_, err := tx.NewDelete().Model(schedule).WherePK().Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		writeScheduleError(c, err, "Schedule not found", "Failed to delete schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Schedule deleted",
+	})
+}
+
+// lockSchedule loads a schedule user may see and change, locking the row.
+func lockSchedule(ctx context.Context, tx bun.Tx, user *models.User, scheduleID int, schedule *models.RequestSchedule) error {
+	query := tx.NewSelect().
+		Model(schedule).
+		Relation("Room").
+		Where("rs.id = ?", scheduleID).
+		For("UPDATE OF rs")
+	if err := scopeRooms(query, user, "rs.room_id").Scan(ctx); err != nil {
+		return err
+	}
+
+	switch user.Role {
+	case models.RoleWarden, models.RoleAdmin:
+		return nil
+	default:
+		if schedule.CreatedBy == nil || *schedule.CreatedBy != user.ID {
+			return errScheduleForbidden
+		}
+		return nil
+	}
+}
+
+// applyScheduleDates parses the optional start date and priority onto
+// schedule. A new schedule without a start date starts today; one without a
+// priority is given its request type's default when it is created.
+func applyScheduleDates(schedule *models.RequestSchedule, startsOn, priority string) error {
+	if value := strings.TrimSpace(startsOn); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return &scheduleError{"starts_on must be a date such as 2026-01-31"}
+		}
+		schedule.StartsOn = date
+	} else if schedule.StartsOn.IsZero() {
+		now := time.Now().In(workflow.ServiceLocation())
+		schedule.StartsOn = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	if value := strings.TrimSpace(priority); value != "" {
+		schedule.Priority = models.RequestPriority(strings.ToLower(value))
+		if !schedule.Priority.Valid() {
+			return &scheduleError{"Unsupported priority"}
+		}
+	}
+	return nil
+}
+
+// planSchedule validates the recurrence and visit window and sets NextRunAt to
+// the first occurrence from now. The first occurrence's window must fall
+// inside the block's service hours.
+func planSchedule(ctx context.Context, db bun.IDB, schedule *models.RequestSchedule) error {
+	if _, err := workflow.ScheduleRule(schedule); err != nil {
+		return &scheduleError{"Invalid recurrence: " + err.Error()}
+	}
+	opens, err := workflow.ParseClock(schedule.WindowStart)
+	if err != nil {
+		return &scheduleError{err.Error()}
+	}
+	closes, err := workflow.ParseClock(schedule.WindowEnd)
+	if err != nil {
+		return &scheduleError{err.Error()}
+	}
+	if opens >= closes {
+		return &scheduleError{"window_start must be before window_end"}
+	}
+
+	next, err := workflow.NextScheduleRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return errScheduleEnded
+	}
+	schedule.NextRunAt = next
+
+	window, err := workflow.ScheduleWindow(schedule, next.In(workflow.ServiceLocation()))
+	if err != nil {
+		return err
+	}
+	return workflow.ValidateTimeWindows(ctx, db, schedule.Room.Block, []*models.RequestTimeWindow{window})
+}
+
+func writeScheduleError(c *gin.Context, err error, notFound, failure string) {
+	var validationErr *scheduleError
+	var windowErr *workflow.WindowError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, workflow.ErrUnknownRequestType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported request type"})
+	case errors.Is(err, errRoomForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot schedule requests for this room"})
+	case errors.Is(err, errScheduleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the schedule's creator, wardens and admins can change it"})
+	case errors.Is(err, errScheduleEnded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The recurrence has no upcoming occurrences"})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+	case errors.As(err, &windowErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid time window",
+			"details": "The visit window of the first occurrence falls outside the block's service hours",
+		})
+	default:
+		logger(c).Error("schedule change failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
+
+func scheduleIDParam(c *gin.Context) (int, bool) {
+	scheduleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return 0, false
+	}
+	return scheduleID, true
+}
+
+// trimOptional trims value, treating blank text as absent.
+func trimOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
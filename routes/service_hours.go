@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+type serviceHoursInput struct {
+	Weekday  *int   `json:"weekday" binding:"required,min=0,max=6"`
+	OpensAt  string `json:"opens_at" binding:"required"`
+	ClosesAt string `json:"closes_at" binding:"required"`
+}
+
+type SetServiceHoursRequest struct {
+	Hours []serviceHoursInput `json:"hours" binding:"dive"`
+}
+
+// GetServiceHours returns the service hours of a block, or the defaults when
+// it has none of its own, along with the time zone they are in.
+func GetServiceHours(c *gin.Context) {
+	block := strings.TrimSpace(c.Param("block"))
+	if !canManageBlock(currentUser(c), block) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wardens can only manage their own block"})
+		return
+	}
+
+	hours, err := workflow.ServiceHours(c.Request.Context(), database.DB, block)
+	if err != nil {
+		logger(c).Error("load service hours failed", "block", block, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"block":    block,
+		"timezone": workflow.ServiceLocation().String(),
+		"hours":    hours,
+	})
+}
+
+// SetServiceHours replaces the service hours of a block. Preferred visit
+// windows on new requests must fall inside them. An empty list restores the
+// defaults.
+func SetServiceHours(c *gin.Context) {
+	block := strings.TrimSpace(c.Param("block"))
+	if !canManageBlock(currentUser(c), block) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wardens can only manage their own block"})
+		return
+	}
+
+	var req SetServiceHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	hours := make([]models.BlockServiceHours, len(req.Hours))
+	for i, input := range req.Hours {
+		opens, err := workflow.ParseClock(input.OpensAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		closes, err := workflow.ParseClock(input.ClosesAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if opens >= closes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "opens_at must be before closes_at"})
+			return
+		}
+		hours[i] = models.BlockServiceHours{
+			Block:    block,
+			Weekday:  *input.Weekday,
+			OpensAt:  strings.TrimSpace(input.OpensAt),
+			ClosesAt: strings.TrimSpace(input.ClosesAt),
+		}
+	}
+
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*models.BlockServiceHours)(nil)).
+			Where("lower(block) = lower(?)", block).
+			Exec(ctx); err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&hours).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		logger(c).Error("set service hours failed", "block", block, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save service hours"})
+		return
+	}
+
+	GetServiceHours(c)
+}
+
+// canManageBlock reports whether user may change settings for block: admins
+// for any block, wardens for their own.
+func canManageBlock(user *models.User, block string) bool {
+	if user.Role == models.RoleAdmin {
+		return true
+	}
+	return user.Role == models.RoleWarden && user.Block != nil &&
+		strings.EqualFold(strings.TrimSpace(*user.Block), block)
+}
@@ -0,0 +1,372 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RefreshRequest represents the token refresh request body
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse carries the credentials issued on signin and refresh
+type TokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	TokenType            string    `json:"token_type"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+	RefreshToken         string    `json:"refresh_token"`
+	RefreshTokenExpires  time.Time `json:"refresh_token_expires_at"`
+	SessionID            uuid.UUID `json:"session_id"`
+}
+
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+// sessionTouchInterval limits how often RequireAuth writes last_seen_at.
+const sessionTouchInterval = time.Minute
+
+// Refresh exchanges a refresh token for a new access token. Refresh tokens
+// are single use: each call rotates the presented token, and presenting an
+// already rotated token revokes the whole session.
+func Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var tokens *TokenResponse
+	reused := false
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		current := new(models.RefreshToken)
+		if err := tx.NewSelect().
+			Model(current).
+			Where("token_hash = ?", auth.HashToken(req.RefreshToken)).
+			For("UPDATE").
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidRefreshToken
+			}
+			return err
+		}
+
+		if current.SessionID == nil {
+			return errInvalidRefreshToken
+		}
+
+		if current.RevokedAt != nil {
+			logger(c).Warn("revoked refresh token reused; revoking session", "session_id", *current.SessionID)
+			if err := revokeSession(ctx, tx, *current.SessionID); err != nil {
+				return err
+			}
+			// Commit the revocation; the caller still rejects the request.
+			reused = true
+			return nil
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return errInvalidRefreshToken
+		}
+
+		session := new(models.Session)
+		if err := tx.NewSelect().
+			Model(session).
+			Where("id = ?", *current.SessionID).
+			Where("revoked_at IS NULL").
+			Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errInvalidRefreshToken
+			}
+			return err
+		}
+
+		issued, replacement, err := issueTokens(ctx, tx, session)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(current).
+			Set("revoked_at = now()").
+			Set("replaced_by = ?", replacement.ID).
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(session).
+			Set("last_seen_at = now()").
+			Set("expires_at = ?", replacement.ExpiresAt).
+			Set("ip = ?", c.ClientIP()).
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		tokens = issued
+		return nil
+	})
+
+	if err == nil && reused {
+		err = errRefreshTokenReused
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, errRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Refresh token has already been used",
+			})
+		case errors.Is(err, errInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired refresh token",
+			})
+		default:
+			logger(c).Error("refresh token rotation failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to refresh token",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the session the request was made with.
+func Logout(c *gin.Context) {
+	session := currentSession(c)
+	if err := revokeSession(c.Request.Context(), database.DB, session.ID); err != nil {
+		logger(c).Error("revoke session failed", "session_id", session.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out"})
+}
+
+// ListSessions returns the signed-in user's active sessions, most recently
+// used first.
+func ListSessions(c *gin.Context) {
+	user := currentUser(c)
+	current := currentSession(c)
+
+	var sessions []models.Session
+	if err := database.DB.NewSelect().
+		Model(&sessions).
+		Where("user_id = ?", user.ID).
+		Where("revoked_at IS NULL").
+		Where("expires_at > now()").
+		Order("last_seen_at DESC").
+		Scan(c.Request.Context()); err != nil {
+		logger(c).Error("list sessions failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession signs out one of the signed-in user's sessions, for example
+// a lost phone.
+func RevokeSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session id"})
+		return
+	}
+
+	user := currentUser(c)
+	ctx := c.Request.Context()
+
+	exists, err := database.DB.NewSelect().
+		Model((*models.Session)(nil)).
+		Where("id = ?", sessionID).
+		Where("user_id = ?", user.ID).
+		Exists(ctx)
+	if err != nil {
+		logger(c).Error("session lookup failed", "session_id", sessionID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := revokeSession(ctx, database.DB, sessionID); err != nil {
+		logger(c).Error("revoke session failed", "session_id", sessionID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeUserSessions signs the user in the :id path parameter out of every
+// session.
+func RevokeUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	exists, err := database.DB.NewSelect().
+		Model((*models.User)(nil)).
+		Where("id = ?", userID).
+		Exists(ctx)
+	if err != nil {
+		logger(c).Error("user lookup failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := revokeUserSessions(ctx, database.DB, userID); err != nil {
+		logger(c).Error("revoke user sessions failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+}
+
+// startSession records a new session for the user and issues its first
+// token pair.
+func startSession(ctx context.Context, db bun.IDB, userID uuid.UUID, userAgent, ip string) (*TokenResponse, error) {
+	session := &models.Session{
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL()),
+	}
+	if _, err := db.NewInsert().Model(session).Returning("*").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	tokens, _, err := issueTokens(ctx, db, session)
+	return tokens, err
+}
+
+// issueTokens signs a new access token and persists a new refresh token for
+// the session.
+func issueTokens(ctx context.Context, db bun.IDB, session *models.Session) (*TokenResponse, *models.RefreshToken, error) {
+	accessToken, accessExpiresAt, err := auth.IssueAccessToken(session.UserID, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := &models.RefreshToken{
+		UserID:    session.UserID,
+		SessionID: &session.ID,
+		TokenHash: refreshHash,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL()),
+	}
+	if _, err := db.NewInsert().Model(record).Returning("id").Exec(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:          accessToken,
+		TokenType:            "Bearer",
+		AccessTokenExpiresAt: accessExpiresAt,
+		RefreshToken:         refreshToken,
+		RefreshTokenExpires:  record.ExpiresAt,
+		SessionID:            session.ID,
+	}, record, nil
+}
+
+// revokeSession revokes a session and its outstanding refresh tokens.
+// RequireAuth rejects its access tokens from then on.
+func revokeSession(ctx context.Context, db bun.IDB, sessionID uuid.UUID) error {
+	if _, err := db.NewUpdate().
+		Model((*models.Session)(nil)).
+		Set("revoked_at = now()").
+		Where("id = ?", sessionID).
+		Where("revoked_at IS NULL").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewUpdate().
+		Model((*models.RefreshToken)(nil)).
+		Set("revoked_at = now()").
+		Where("session_id = ?", sessionID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+// revokeUserSessions revokes every session and refresh token the user holds.
+func revokeUserSessions(ctx context.Context, db bun.IDB, userID uuid.UUID) error {
+	if _, err := db.NewUpdate().
+		Model((*models.Session)(nil)).
+		Set("revoked_at = now()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewUpdate().
+		Model((*models.RefreshToken)(nil)).
+		Set("revoked_at = now()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
+
+// revokeOtherSessions revokes every session the user holds except keep, and
+// all refresh tokens including keep's so its next pair has to be issued
+// fresh.
+func revokeOtherSessions(ctx context.Context, db bun.IDB, userID, keep uuid.UUID) error {
+	if _, err := db.NewUpdate().
+		Model((*models.Session)(nil)).
+		Set("revoked_at = now()").
+		Where("user_id = ?", userID).
+		Where("id <> ?", keep).
+		Where("revoked_at IS NULL").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewUpdate().
+		Model((*models.RefreshToken)(nil)).
+		Set("revoked_at = now()").
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/gin-gonic/gin"
+)
+
+type UpsertSLAPolicyRequest struct {
+	ResolveWithinMinutes int `json:"resolve_within_minutes" binding:"required,min=1"`
+	WarnBeforeMinutes    int `json:"warn_before_minutes" binding:"min=0"`
+}
+
+// ListSLAPolicies returns the SLA target for every request type and priority
+// that has one.
+func ListSLAPolicies(c *gin.Context) {
+	var policies []models.SLAPolicy
+	if err := database.DB.NewSelect().
+		Model(&policies).
+		Order("sla.type ASC", "sla.resolve_within_minutes ASC").
+		Scan(c.Request.Context()); err != nil {
+		logger(c).Error("list sla policies failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SLA policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// UpsertSLAPolicy sets the SLA target for one request type and priority.
+// Deadlines of existing requests are left alone; new requests and priority
+// changes pick up the new target.
+func UpsertSLAPolicy(c *gin.Context) {
+	requestType, priority, ok := slaPolicyKey(c)
+	if !ok {
+		return
+	}
+
+	var req UpsertSLAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.WarnBeforeMinutes >= req.ResolveWithinMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "warn_before_minutes must be less than resolve_within_minutes"})
+		return
+	}
+
+	policy := &models.SLAPolicy{
+		Type:                 requestType,
+		Priority:             priority,
+		ResolveWithinMinutes: req.ResolveWithinMinutes,
+		WarnBeforeMinutes:    req.WarnBeforeMinutes,
+	}
+	if _, err := database.DB.NewInsert().
+		Model(policy).
+		On("CONFLICT (type, priority) DO UPDATE").
+		Set("resolve_within_minutes = EXCLUDED.resolve_within_minutes").
+		Set("warn_before_minutes = EXCLUDED.warn_before_minutes").
+		Set("updated_at = now()").
+		Returning("*").
+		Exec(c.Request.Context()); err != nil {
+		logger(c).Error("upsert sla policy failed", "type", requestType, "priority", priority, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save SLA policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "SLA policy saved",
+		"policy":  policy,
+	})
+}
+
+// DeleteSLAPolicy removes the SLA target for one request type and priority.
+// New requests of that kind are no longer tracked.
+func DeleteSLAPolicy(c *gin.Context) {
+	requestType, priority, ok := slaPolicyKey(c)
+	if !ok {
+		return
+	}
+
+	res, err := database.DB.NewDelete().
+		Model((*models.SLAPolicy)(nil)).
+		Where("type = ?", requestType).
+		Where("priority = ?", priority).
+		Exec(c.Request.Context())
+	if err != nil {
+		logger(c).Error("delete sla policy failed", "type", requestType, "priority", priority, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SLA policy"})
+		return
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLA policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLA policy deleted"})
+}
+
+// ListSLABreaches returns recorded SLA breaches, newest first. Wardens only
+// see breaches in their block.
+func ListSLABreaches(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var breaches []models.SLABreach
+	query := database.DB.NewSelect().
+		Model(&breaches).
+		Relation("Request").
+		Relation("Request.Room").
+		Order("slab.breached_at DESC").
+		Limit(limit).
+		Offset(offset)
+
+	user := currentUser(c)
+	if user.Role != models.RoleAdmin {
+		if user.Block == nil || strings.TrimSpace(*user.Block) == "" {
+			query = query.Where("FALSE")
+		} else {
+			query = query.Where("request__room.block = ?", strings.TrimSpace(*user.Block))
+		}
+	}
+
+	switch c.Query("escalated") {
+	case "":
+	case "true":
+		query = query.Where("slab.escalated_at IS NOT NULL")
+	case "false":
+		query = query.Where("slab.escalated_at IS NULL")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "escalated must be true or false"})
+		return
+	}
+
+	total, err := query.ScanAndCount(c.Request.Context())
+	if err != nil {
+		logger(c).Error("list sla breaches failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SLA breaches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"breaches": breaches,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// slaPolicyKey parses the :type and :priority path parameters, writing a 400
+// response and returning ok=false when either is unknown.
+func slaPolicyKey(c *gin.Context) (models.RequestType, models.RequestPriority, bool) {
+	requestType := workflow.NormalizeRequestType(c.Param("type"))
+	if !knownRequestType(c, requestType) {
+		return "", "", false
+	}
+	priority := models.RequestPriority(strings.ToLower(c.Param("priority")))
+	if !priority.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported priority"})
+		return "", "", false
+	}
+	return requestType, priority, true
+}
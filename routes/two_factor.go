@@ -0,0 +1,444 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/auth"
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorSignInRequest represents the second sign-in step body. Either a
+// TOTP code or a recovery code must be supplied.
+type TwoFactorSignInRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the disable request body
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// twoFactorRequired reports whether accounts with role must enroll in TOTP,
+// as configured by TWO_FACTOR_REQUIRED_ROLES.
+func twoFactorRequired(role models.Role) bool {
+	roles := config.List("TWO_FACTOR_REQUIRED_ROLES", []string{string(models.RoleWarden), string(models.RoleAdmin)})
+	return slices.Contains(roles, string(role))
+}
+
+// SignInTwoFactor completes a sign-in that SignIn answered with a challenge
+// token.
+func SignInTwoFactor(c *gin.Context) {
+	var req TwoFactorSignInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	claims, err := auth.ParseTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid or expired challenge token",
+			"success": false,
+		})
+		return
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid or expired challenge token",
+			"success": false,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	policy := currentLoginPolicy()
+
+	user := new(models.User)
+	if err := database.DB.NewSelect().Model(user).Where("id = ?", userID).Scan(ctx); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid or expired challenge token",
+			"success": false,
+		})
+		return
+	}
+
+	if reason := loginBlocked(user, policy); reason != "" {
+		logger(c).Info("two-factor sign-in blocked", "user_id", user.ID, "reason", reason)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid authentication code",
+			"success": false,
+		})
+		return
+	}
+
+	ok, err := verifySecondFactor(ctx, database.DB, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		logger(c).Error("second factor verification failed", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify code",
+			"success": false,
+		})
+		return
+	}
+
+	if !ok {
+		ipFailures, err := ipFailureCount(ctx, database.DB, ip, policy.IPWindow)
+		if err != nil {
+			logger(c).Error("ip failure count failed", "error", err)
+		}
+		if _, err := recordFailedLogin(ctx, database.DB, user, ip, policy); err != nil {
+			logger(c).Error("record failed login failed", "user_id", user.ID, "error", err)
+		}
+		recordSignInFailure(ctx, user.Email, &user.ID, ip, ipFailures, policy)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid authentication code",
+			"success": false,
+		})
+		return
+	}
+
+	completeSignIn(c, user, user.Email, ip)
+}
+
+// SetupTwoFactor generates a new TOTP secret for the signed-in user. The
+// secret is not enforced until EnableTwoFactor confirms a code from it.
+func SetupTwoFactor(c *gin.Context) {
+	user := currentUser(c)
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		logger(c).Error("totp secret generation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	if _, err := database.DB.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("totp_secret = ?", secret).
+		Set("totp_last_step = NULL").
+		Where("id = ?", user.ID).
+		Exec(c.Request.Context()); err != nil {
+		logger(c).Error("save totp secret failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	issuer := config.String("TOTP_ISSUER", "DBMS")
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(issuer, user.Email, secret),
+	})
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator app
+// and returns a fresh set of recovery codes. The codes are only shown once.
+func EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment before enabling two-factor authentication"})
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(*user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.RunInTx(c.Request.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().
+			Model((*models.User)(nil)).
+			Set("totp_enabled_at = now()").
+			Set("totp_last_step = ?", step).
+			Where("id = ?", user.ID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(ctx, tx, user.ID)
+		return err
+	})
+	if err != nil {
+		logger(c).Error("enable two-factor failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off TOTP for the signed-in user after re-checking
+// their password and a second factor. Roles that require two-factor
+// authentication cannot disable it.
+func DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if twoFactorRequired(user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+
+	if !checkPassword(c, user, req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ok, err := verifySecondFactor(ctx, database.DB, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		logger(c).Error("second factor verification failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	if err := clearTwoFactor(ctx, database.DB, user.ID); err != nil {
+		logger(c).Error("disable two-factor failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the signed-in user's recovery codes after
+// checking a current TOTP code.
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ok, err := verifySecondFactor(ctx, database.DB, user, req.Code, "")
+	if err != nil {
+		logger(c).Error("second factor verification failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, database.DB, user.ID)
+	if err != nil {
+		logger(c).Error("replace recovery codes failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ResetUserTwoFactor removes TOTP enrollment for the user in the :id path
+// parameter, for example after a lost phone, and signs them out everywhere.
+func ResetUserTwoFactor(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	err = database.DB.RunInTx(c.Request.Context(), nil, func(ctx context.Context, tx bun.Tx) error {
+		exists, err := tx.NewSelect().Model((*models.User)(nil)).Where("id = ?", userID).Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+
+		if err := clearTwoFactor(ctx, tx, userID); err != nil {
+			return err
+		}
+		return revokeUserSessions(ctx, tx, userID)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger(c).Error("reset two-factor failed", "target_user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// verifySecondFactor checks a TOTP code or, failing that, consumes a
+// recovery code. TOTP codes are bound to their time step so each one can only
+// be used once.
+func verifySecondFactor(ctx context.Context, db bun.IDB, user *models.User, code, recoveryCode string) (bool, error) {
+	if user.TOTPSecret == nil || user.TOTPEnabledAt == nil {
+		return false, nil
+	}
+
+	if strings.TrimSpace(code) != "" {
+		step, ok := auth.ValidateTOTP(*user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		result, err := db.NewUpdate().
+			Model((*models.User)(nil)).
+			Set("totp_last_step = ?", step).
+			Where("id = ?", user.ID).
+			Where("totp_last_step IS NULL OR totp_last_step < ?", step).
+			Exec(ctx)
+		if err != nil {
+			return false, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		return affected == 1, nil
+	}
+
+	normalized := auth.NormalizeRecoveryCode(recoveryCode)
+	if normalized == "" {
+		return false, nil
+	}
+
+	result, err := db.NewUpdate().
+		Model((*models.RecoveryCode)(nil)).
+		Set("used_at = now()").
+		Where("user_id = ?", user.ID).
+		Where("code_hash = ?", auth.HashToken(normalized)).
+		Where("used_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a new
+// set, returning the plaintext codes.
+func replaceRecoveryCodes(ctx context.Context, db bun.IDB, userID uuid.UUID) ([]string, error) {
+	if _, err := db.NewDelete().
+		Model((*models.RecoveryCode)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		})
+	}
+	if _, err := db.NewInsert().Model(&records).Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// clearTwoFactor removes the user's TOTP secret and recovery codes.
+func clearTwoFactor(ctx context.Context, db bun.IDB, userID uuid.UUID) error {
+	if _, err := db.NewUpdate().
+		Model((*models.User)(nil)).
+		Set("totp_secret = NULL").
+		Set("totp_enabled_at = NULL").
+		Set("totp_last_step = NULL").
+		Where("id = ?", userID).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewDelete().
+		Model((*models.RecoveryCode)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,221 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/database"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
+)
+
+// UpdateProfileRequest represents the profile update request body. Omitted
+// fields are left unchanged; an empty phone, block or room_name clears it.
+type UpdateProfileRequest struct {
+	Name     *string `json:"name"`
+	Phone    *string `json:"phone"`
+	Block    *string `json:"block"`
+	RoomName *string `json:"room_name"`
+}
+
+// ChangePasswordRequest represents the password change request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePasswordResponse carries the fresh token pair for the current session
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+	*TokenResponse
+}
+
+var errRoomWithoutBlock = errors.New("block is required when room_name is provided")
+
+// GetProfile returns the signed-in user.
+func GetProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"user": currentUser(c)})
+}
+
+// UpdateProfile edits the signed-in user's name, phone, block and room.
+// Moving rooms swaps the user's room_members link the same way VerifyEmail
+// creates it; unverified users are linked once they verify. When self-signup
+// is off residents are placed by invitation, so only wardens and
+// administrators may move them.
+func UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+
+	movingRoom := req.Block != nil || req.RoomName != nil
+	if movingRoom && user.Role != models.RoleResident {
+		// A staff member's block decides which requests they can see, so
+		// only an administrator may change it.
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only residents can change their block or room; ask an administrator"})
+		return
+	}
+	if movingRoom && !selfSignupEnabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room changes are handled by wardens and administrators"})
+		return
+	}
+
+	updated := new(models.User)
+	ctx := c.Request.Context()
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().Model(updated).Where("id = ?", user.ID).For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		previous := *updated
+
+		if req.Name != nil {
+			updated.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Phone != nil {
+			updated.Phone = optionalString(*req.Phone)
+		}
+		if req.Block != nil {
+			updated.Block = optionalString(*req.Block)
+		}
+		if req.RoomName != nil {
+			updated.RoomName = optionalString(*req.RoomName)
+		}
+
+		if updated.RoomName != nil && updated.Block == nil {
+			return errRoomWithoutBlock
+		}
+
+		if _, err := tx.NewUpdate().
+			Model(updated).
+			Column("name", "phone", "block", "room_name").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if !movingRoom || updated.EmailVerifiedAt == nil || sameProfileRoom(&previous, updated) {
+			return nil
+		}
+		if err := unlinkProfileRoom(ctx, tx, &previous); err != nil {
+			return err
+		}
+		return linkProfileRoom(ctx, tx, updated)
+	})
+
+	if err != nil {
+		if errors.Is(err, errRoomWithoutBlock) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "block is required when room_name is provided"})
+			return
+		}
+		logger(c).Error("update profile failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated",
+		"user":    updated,
+	})
+}
+
+// ChangePassword replaces the signed-in user's password after re-checking the
+// current one. Every other session is signed out and the current session gets
+// a fresh token pair, since tokens issued before the change stop working.
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := currentUser(c)
+	session := currentSession(c)
+
+	if !checkPassword(c, user, req.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current password"})
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
+	if err != nil {
+		logger(c).Error("password hash failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	var tokens *TokenResponse
+	ctx := c.Request.Context()
+	err = database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().
+			Model((*models.User)(nil)).
+			Set("password = ?", hashedPassword).
+			Set("password_changed_at = now()").
+			Where("id = ?", user.ID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if err := revokeOtherSessions(ctx, tx, user.ID, session.ID); err != nil {
+			return err
+		}
+
+		var err error
+		tokens, _, err = issueTokens(ctx, tx, session)
+		return err
+	})
+
+	if err != nil {
+		logger(c).Error("change password failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ChangePasswordResponse{
+		Message:       "Password changed. Other sessions have been signed out.",
+		TokenResponse: tokens,
+	})
+}
+
+// optionalString trims value and returns nil when it is empty.
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// sameProfileRoom reports whether a and b have the same block and room on
+// their profiles.
+func sameProfileRoom(a, b *models.User) bool {
+	return derefString(a.Block) == derefString(b.Block) &&
+		derefString(a.RoomName) == derefString(b.RoomName)
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
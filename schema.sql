@@ -9,7 +9,19 @@ CREATE TABLE users (
     block TEXT,
     room_name TEXT,
     phone TEXT,
-    created_at TIMESTAMP DEFAULT now()
+    role TEXT NOT NULL DEFAULT 'resident',
+    email_verified_at TIMESTAMP,
+    password_changed_at TIMESTAMP,
+    failed_login_count INT NOT NULL DEFAULT 0,
+    last_failed_login_at TIMESTAMP,
+    locked_until TIMESTAMP,
+    totp_secret TEXT,
+    totp_enabled_at TIMESTAMP,
+    totp_last_step BIGINT,
+    deactivated_at TIMESTAMP,
+    on_call BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT now(),
+    CONSTRAINT users_role_check CHECK (role IN ('resident', 'cleaner', 'technician', 'warden', 'admin'))
 );
 
 -- ==============================
@@ -32,11 +44,42 @@ CREATE TABLE room_members (
     block TEXT NOT NULL,
     user_id UUID NOT NULL,
     joined_at TIMESTAMP DEFAULT now(),
+    active BOOLEAN NOT NULL DEFAULT true,
     PRIMARY KEY (room_id, block, user_id),
     CONSTRAINT fk_room_members_room FOREIGN KEY (room_id, block) REFERENCES rooms(id, block) ON DELETE CASCADE,
     CONSTRAINT fk_room_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+-- ==============================
+-- REQUEST TYPES
+-- ==============================
+-- category decides which staff handle the type: cleaners for cleaning,
+-- technicians for maintenance. The SLA columns apply to priorities without
+-- their own row in sla_policies.
+CREATE TABLE request_types (
+    name TEXT PRIMARY KEY CHECK (name ~ '^[a-z][a-z0-9_]*$'),
+    label TEXT NOT NULL,
+    category TEXT NOT NULL CHECK (category IN ('cleaning', 'maintenance')),
+    default_priority TEXT NOT NULL DEFAULT 'normal' CHECK (default_priority IN ('low', 'normal', 'high', 'emergency')),
+    sla_resolve_within_minutes INT CHECK (sla_resolve_within_minutes > 0),
+    sla_warn_before_minutes INT NOT NULL DEFAULT 0 CHECK (sla_warn_before_minutes >= 0),
+    one_active_per_room BOOLEAN NOT NULL DEFAULT true,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+INSERT INTO request_types (name, label, category, sla_resolve_within_minutes, sla_warn_before_minutes)
+VALUES
+    ('cleaning', 'Cleaning', 'cleaning', 1440, 240),
+    ('maintenance', 'General maintenance', 'maintenance', 4320, 720),
+    ('electrical', 'Electrical', 'maintenance', 1440, 240),
+    ('plumbing', 'Plumbing', 'maintenance', 1440, 240),
+    ('carpentry', 'Carpentry', 'maintenance', 4320, 720),
+    ('pest_control', 'Pest control', 'cleaning', 4320, 720),
+    ('internet', 'Internet', 'maintenance', 1440, 240),
+    ('furniture', 'Furniture', 'maintenance', 4320, 720);
+
 -- ==============================
 -- REQUESTS TABLE
 -- ==============================
@@ -44,17 +87,377 @@ CREATE TABLE requests (
     id SERIAL PRIMARY KEY,
     user_id UUID REFERENCES users(id) ON DELETE SET NULL,
     room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
-    type TEXT CHECK (type IN ('cleaning', 'maintenance')) NOT NULL,
-    status TEXT CHECK (status IN ('active', 'completed', 'cancelled')) DEFAULT 'active',
+    type TEXT NOT NULL REFERENCES request_types(name),
+    status TEXT DEFAULT 'active',
     description TEXT,
     created_at TIMESTAMP DEFAULT now(),
-    updated_at TIMESTAMP DEFAULT now()
+    updated_at TIMESTAMP DEFAULT now(),
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    assigned_at TIMESTAMP,
+    suggested_priority TEXT NOT NULL DEFAULT 'normal',
+    priority TEXT NOT NULL DEFAULT 'normal',
+    priority_confirmed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    priority_confirmed_at TIMESTAMP,
+    sla_due_at TIMESTAMP,
+    sla_at_risk_at TIMESTAMP,
+    sla_breached_at TIMESTAMP,
+    one_active BOOLEAN NOT NULL DEFAULT true,
+    CONSTRAINT requests_status_check
+        CHECK (status IN ('active', 'assigned', 'in_progress', 'on_hold', 'awaiting_parts', 'completed', 'cancelled')),
+    CONSTRAINT requests_suggested_priority_check
+        CHECK (suggested_priority IN ('low', 'normal', 'high', 'emergency')),
+    CONSTRAINT requests_priority_check
+        CHECK (priority IN ('low', 'normal', 'high', 'emergency'))
 );
 
+CREATE INDEX idx_requests_assignee ON requests (assignee_id);
+
+CREATE INDEX idx_requests_open_emergencies ON requests (created_at)
+WHERE priority = 'emergency' AND status NOT IN ('completed', 'cancelled');
+
+CREATE INDEX idx_requests_sla_due ON requests (sla_due_at)
+WHERE sla_breached_at IS NULL AND status NOT IN ('completed', 'cancelled');
+
 -- ==============================
 -- CONSTRAINTS
 -- ==============================
--- One active request per room per type
-CREATE UNIQUE INDEX unique_active_request_per_room_type
+-- One open (non-terminal) request per room per type, for types filed with
+-- one_active_per_room set
+CREATE UNIQUE INDEX unique_open_request_per_room_type
 ON requests (room_id, type)
-WHERE status = 'active';
+WHERE one_active AND status NOT IN ('completed', 'cancelled');
+
+-- Keep updated_at current on every update
+CREATE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER requests_set_updated_at
+BEFORE UPDATE ON requests
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER request_types_set_updated_at
+BEFORE UPDATE ON request_types
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+
+-- ==============================
+-- SESSIONS
+-- ==============================
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT now(),
+    last_seen_at TIMESTAMP DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_user ON sessions (user_id);
+
+-- ==============================
+-- REFRESH TOKENS
+-- ==============================
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    session_id UUID REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens (user_id);
+
+-- ==============================
+-- PASSWORD RESET TOKENS
+-- ==============================
+CREATE TABLE password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT false,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_password_reset_tokens_user ON password_reset_tokens (user_id);
+
+-- ==============================
+-- EMAIL VERIFICATION TOKENS
+-- ==============================
+CREATE TABLE email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_email_verification_tokens_user ON email_verification_tokens (user_id);
+
+-- ==============================
+-- LOGIN ATTEMPTS
+-- ==============================
+CREATE TABLE login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_login_attempts_ip_created ON login_attempts (ip, created_at);
+
+-- ==============================
+-- SECURITY EVENTS
+-- ==============================
+CREATE TABLE security_events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip TEXT,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_security_events_created ON security_events (created_at);
+
+-- ==============================
+-- TWO-FACTOR RECOVERY CODES
+-- ==============================
+CREATE TABLE two_factor_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_two_factor_recovery_codes_user ON two_factor_recovery_codes (user_id);
+
+-- ==============================
+-- EXTERNAL IDENTITIES (OIDC)
+-- ==============================
+CREATE TABLE identities (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    created_at TIMESTAMP DEFAULT now(),
+    last_login_at TIMESTAMP,
+    CONSTRAINT unique_identity_issuer_subject UNIQUE (issuer, subject)
+);
+
+CREATE INDEX idx_identities_user ON identities (user_id);
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'personal' CHECK (kind IN ('personal', 'service')),
+    prefix TEXT UNIQUE NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys (user_id);
+
+CREATE TABLE invitations (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    room_id INT NOT NULL,
+    block TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'resident' CHECK (role IN ('resident', 'cleaner', 'technician', 'warden', 'admin')),
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMP,
+    accepted_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now(),
+    CONSTRAINT fk_invitations_room FOREIGN KEY (room_id, block) REFERENCES rooms(id, block) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_invitations_email ON invitations (lower(email));
+
+CREATE TABLE request_events (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    type TEXT NOT NULL,
+    field TEXT,
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_request_events_request ON request_events (request_id, created_at);
+
+CREATE TABLE request_comments (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    internal BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT now(),
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_request_comments_request ON request_comments (request_id, created_at);
+
+CREATE TABLE request_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT UNIQUE NOT NULL,
+    thumbnail_key TEXT,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_request_attachments_request ON request_attachments (request_id, created_at);
+
+CREATE TABLE sla_policies (
+    id SERIAL PRIMARY KEY,
+    type TEXT NOT NULL REFERENCES request_types(name) ON DELETE CASCADE,
+    priority TEXT NOT NULL CHECK (priority IN ('low', 'normal', 'high', 'emergency')),
+    resolve_within_minutes INT NOT NULL CHECK (resolve_within_minutes > 0),
+    warn_before_minutes INT NOT NULL DEFAULT 0 CHECK (warn_before_minutes >= 0),
+    updated_at TIMESTAMP DEFAULT now(),
+    CONSTRAINT sla_policies_type_priority_key UNIQUE (type, priority)
+);
+
+-- Cleaning within a day and maintenance within three days, with emergencies
+-- of any type within four hours.
+INSERT INTO sla_policies (type, priority, resolve_within_minutes, warn_before_minutes)
+VALUES
+    ('cleaning', 'low', 1440, 240),
+    ('cleaning', 'normal', 1440, 240),
+    ('cleaning', 'high', 1440, 240),
+    ('cleaning', 'emergency', 240, 60),
+    ('maintenance', 'low', 4320, 720),
+    ('maintenance', 'normal', 4320, 720),
+    ('maintenance', 'high', 4320, 720),
+    ('maintenance', 'emergency', 240, 60),
+    ('electrical', 'emergency', 240, 60),
+    ('plumbing', 'emergency', 240, 60),
+    ('carpentry', 'emergency', 240, 60),
+    ('pest_control', 'emergency', 240, 60),
+    ('internet', 'emergency', 240, 60),
+    ('furniture', 'emergency', 240, 60);
+
+CREATE TABLE sla_breaches (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    due_at TIMESTAMP NOT NULL,
+    breached_at TIMESTAMP DEFAULT now(),
+    escalated_at TIMESTAMP,
+    CONSTRAINT sla_breaches_request_due_key UNIQUE (request_id, due_at)
+);
+
+-- Service hours per block and weekday (0 = Sunday), in SERVICE_TIMEZONE.
+-- Blocks without rows use SERVICE_HOURS_DEFAULT every day.
+CREATE TABLE block_service_hours (
+    id SERIAL PRIMARY KEY,
+    block TEXT NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    CONSTRAINT block_service_hours_span_check CHECK (opens_at < closes_at)
+);
+
+CREATE INDEX idx_block_service_hours_block ON block_service_hours (lower(block), weekday);
+
+CREATE TABLE request_time_windows (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    CONSTRAINT request_time_windows_span_check CHECK (starts_at < ends_at)
+);
+
+CREATE INDEX idx_request_time_windows_request ON request_time_windows (request_id, starts_at);
+
+CREATE TABLE request_access_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    attempted_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_request_access_attempts_request ON request_access_attempts (request_id, attempted_at);
+
+CREATE TABLE request_schedules (
+    id SERIAL PRIMARY KEY,
+    room_id INT NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    type TEXT NOT NULL REFERENCES request_types(name),
+    priority TEXT NOT NULL DEFAULT 'normal' CHECK (priority IN ('low', 'normal', 'high', 'emergency')),
+    description TEXT,
+    recurrence TEXT NOT NULL,
+    starts_on DATE NOT NULL,
+    window_start TIME NOT NULL,
+    window_end TIME NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now(),
+    CONSTRAINT request_schedules_window_check CHECK (window_start < window_end)
+);
+
+CREATE INDEX idx_request_schedules_due ON request_schedules (next_run_at) WHERE active;
+
+CREATE TRIGGER request_schedules_set_updated_at
+BEFORE UPDATE ON request_schedules
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Requests filed by a recurring schedule point back at it
+ALTER TABLE requests ADD COLUMN schedule_id INT REFERENCES request_schedules(id) ON DELETE SET NULL;
+
+CREATE TABLE request_confirmations (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INT NOT NULL REFERENCES requests(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'confirmed', 'disputed', 'auto_accepted')),
+    completed_at TIMESTAMP DEFAULT now(),
+    auto_accept_at TIMESTAMP NOT NULL,
+    dispute_until TIMESTAMP NOT NULL,
+    responded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    responded_at TIMESTAMP,
+    rating SMALLINT CHECK (rating BETWEEN 1 AND 5),
+    feedback TEXT
+);
+
+CREATE INDEX idx_request_confirmations_request ON request_confirmations (request_id, completed_at);
+
+-- At most one confirmation per request waits for an answer
+CREATE UNIQUE INDEX unique_pending_confirmation_per_request
+ON request_confirmations (request_id)
+WHERE status = 'pending';
+
+CREATE INDEX idx_request_confirmations_auto_accept ON request_confirmations (auto_accept_at)
+WHERE status = 'pending';
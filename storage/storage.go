@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/config"
+)
+
+// ErrNotFound is returned when no blob exists under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files. Keys are slash-separated paths chosen by the
+// caller, such as "requests/42/<uuid>".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Default is the store used by the HTTP handlers. It is set by Init.
+var Default BlobStore
+
+// Init selects the blob store from the BLOB_STORE environment variable. The
+// only supported value is "local" (the default), which keeps files under
+// BLOB_DIR.
+func Init() error {
+	switch strings.ToLower(config.String("BLOB_STORE", "local")) {
+	case "local":
+		dir := config.String("BLOB_DIR", "uploads")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create blob directory: %w", err)
+		}
+		Default = LocalStore{Dir: dir}
+	default:
+		return fmt.Errorf("unsupported BLOB_STORE %q", config.String("BLOB_STORE", ""))
+	}
+	return nil
+}
+
+// LocalStore keeps blobs as files under Dir.
+type LocalStore struct {
+	Dir string
+}
+
+func (s LocalStore) Put(_ context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s LocalStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps key to a file under Dir, rejecting keys that would escape it.
+func (s LocalStore) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
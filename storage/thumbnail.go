@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales img down so neither side exceeds size, keeping its aspect
+// ratio, and flattens transparency onto white so it can be encoded as JPEG.
+// Each output pixel averages the source pixels it covers. Images already
+// within size are only flattened.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+			dst.SetRGBA(x, y, average(img, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// average returns the mean colour of the rectangle, composited over white.
+func average(img image.Image, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			// Colours are alpha-premultiplied, so adding the uncovered share
+			// of white composites the pixel over a white background.
+			white := 0xffff - uint64(ca)
+			r += uint64(cr) + white
+			g += uint64(cg) + white
+			b += uint64(cb) + white
+			n++
+		}
+	}
+	return color.RGBA{
+		R: uint8((r / n) >> 8),
+		G: uint8((g / n) >> 8),
+		B: uint8((b / n) >> 8),
+		A: 0xff,
+	}
+}
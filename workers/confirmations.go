@@ -0,0 +1,42 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/uptrace/bun"
+)
+
+// confirmationBatchSize caps how many confirmations one pass locks at a time.
+const confirmationBatchSize = 100
+
+// AutoAcceptConfirmations accepts completion confirmations that room members
+// left unanswered past their deadline. Rows are claimed with SKIP LOCKED, so a
+// confirmation a resident is answering at that moment is left to them.
+func AutoAcceptConfirmations(ctx context.Context, db *bun.DB) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var confirmations []models.RequestConfirmation
+		if err := tx.NewSelect().
+			Model(&confirmations).
+			Where("rcf.status = ?", models.ConfirmationPending).
+			Where("rcf.auto_accept_at <= now()").
+			Order("rcf.auto_accept_at ASC").
+			Limit(confirmationBatchSize).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		for i := range confirmations {
+			if err := workflow.AutoAccept(ctx, tx, &confirmations[i]); err != nil {
+				return err
+			}
+		}
+		if len(confirmations) > 0 {
+			logging.FromContext(ctx).Info("auto-accepted completed requests", "count", len(confirmations))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/notify"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/uptrace/bun"
+)
+
+const (
+	scheduleBatchSize = 100
+	// maxRunsPerSchedule bounds how many occurrences one pass works through
+	// for a schedule that has fallen behind.
+	maxRunsPerSchedule = 50
+)
+
+// RunSchedules files requests for schedule occurrences whose window starts
+// within lead of now, so staff see them in their queues ahead of time.
+// Schedules are claimed with SKIP LOCKED so several instances can run side by
+// side. A schedule that fails is logged and skipped until the next pass.
+// Emergency requests alert the block once the batch commits.
+func RunSchedules(ctx context.Context, db *bun.DB, lead time.Duration) error {
+	var emergencies []*models.Request
+	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		horizon := time.Now().Add(lead)
+
+		var schedules []models.RequestSchedule
+		if err := tx.NewSelect().
+			Model(&schedules).
+			Where("rs.active").
+			Where("rs.next_run_at <= ?", horizon).
+			Order("rs.next_run_at ASC").
+			Limit(scheduleBatchSize).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		log := logging.FromContext(ctx)
+		for i := range schedules {
+			schedule := &schedules[i]
+			// Each schedule runs in its own savepoint so one that fails is
+			// rolled back and retried next pass without undoing the rest.
+			var filed []*models.Request
+			err := tx.RunInTx(ctx, nil, func(ctx context.Context, sp bun.Tx) error {
+				for range maxRunsPerSchedule {
+					if schedule.NextRunAt == nil || schedule.NextRunAt.After(horizon) {
+						break
+					}
+					request, err := workflow.RunSchedule(ctx, sp, schedule)
+					if err != nil {
+						return err
+					}
+					if request != nil {
+						log.Info("scheduled request filed", "schedule_id", schedule.ID, "request_id", request.ID)
+						if request.Priority == models.RequestPriorityEmergency {
+							filed = append(filed, request)
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.Error("schedule run failed", "schedule_id", schedule.ID, "error", err)
+				continue
+			}
+			emergencies = append(emergencies, filed...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Emergency requests are alerted the same way as when filed by hand, once
+	// they are committed.
+	for _, request := range emergencies {
+		if err := notify.Emergency(ctx, db, request); err != nil {
+			logging.FromContext(ctx).Error("emergency alert failed", "request_id", request.ID, "error", err)
+		}
+	}
+	return nil
+}
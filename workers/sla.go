@@ -0,0 +1,140 @@
+package workers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/notify"
+	"github.com/adii2ma/dbms-backend/workflow"
+	"github.com/uptrace/bun"
+)
+
+// slaBatchSize caps how many requests one pass locks at a time.
+const slaBatchSize = 100
+
+// CheckSLAs flags open requests whose deadline is within their policy's
+// warning period, records breaches for those past it and escalates breaches
+// that have not yet reached the block's wardens. Rows are claimed with SKIP
+// LOCKED so several instances can run the checker side by side.
+func CheckSLAs(ctx context.Context, db *bun.DB) error {
+	if err := flagAtRisk(ctx, db); err != nil {
+		return err
+	}
+	if err := recordBreaches(ctx, db); err != nil {
+		return err
+	}
+	return escalateBreaches(ctx, db)
+}
+
+func flagAtRisk(ctx context.Context, db *bun.DB) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var requests []models.Request
+		if err := tx.NewSelect().
+			Model(&requests).
+			Join("JOIN request_types AS t ON t.name = req.type").
+			Join("LEFT JOIN sla_policies AS p ON p.type = req.type AND p.priority = req.priority").
+			Where("req.status IN (?)", bun.In(models.OpenRequestStatuses)).
+			Where("req.sla_at_risk_at IS NULL").
+			Where("req.sla_breached_at IS NULL").
+			Where("req.sla_due_at > now()").
+			Where("req.sla_due_at - COALESCE(p.warn_before_minutes, t.sla_warn_before_minutes) * interval '1 minute' <= now()").
+			Limit(slaBatchSize).
+			For("UPDATE OF req SKIP LOCKED").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		for i := range requests {
+			if err := workflow.MarkSLAAtRisk(ctx, tx, &requests[i]); err != nil {
+				return err
+			}
+		}
+		if len(requests) > 0 {
+			logging.FromContext(ctx).Info("requests at risk of SLA breach", "count", len(requests))
+		}
+		return nil
+	})
+}
+
+func recordBreaches(ctx context.Context, db *bun.DB) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var requests []models.Request
+		if err := tx.NewSelect().
+			Model(&requests).
+			Where("req.status IN (?)", bun.In(models.OpenRequestStatuses)).
+			Where("req.sla_breached_at IS NULL").
+			Where("req.sla_due_at <= now()").
+			Limit(slaBatchSize).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		for i := range requests {
+			if _, err := workflow.RecordSLABreach(ctx, tx, &requests[i]); err != nil {
+				return err
+			}
+		}
+		if len(requests) > 0 {
+			logging.FromContext(ctx).Warn("requests breached their SLA", "count", len(requests))
+		}
+		return nil
+	})
+}
+
+// escalateBreaches notifies wardens of breaches recorded but not yet
+// escalated. Breaches are claimed by stamping escalated_at inside a short
+// transaction and mailed after it commits, so no row stays locked while mail
+// is sent. A breach whose mail fails has the stamp cleared and is retried on
+// the next pass.
+func escalateBreaches(ctx context.Context, db *bun.DB) error {
+	var breaches []models.SLABreach
+	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewSelect().
+			Model(&breaches).
+			Relation("Request").
+			Relation("Request.Room").
+			Where("slab.escalated_at IS NULL").
+			Order("slab.breached_at ASC").
+			Limit(slaBatchSize).
+			For("UPDATE OF slab SKIP LOCKED").
+			Scan(ctx); err != nil {
+			return err
+		}
+		if len(breaches) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(breaches))
+		for i := range breaches {
+			ids[i] = breaches[i].ID
+		}
+		_, err := tx.NewUpdate().
+			Model((*models.SLABreach)(nil)).
+			Set("escalated_at = now()").
+			Where("id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range breaches {
+		breach := &breaches[i]
+		if err := notify.SLABreach(ctx, db, breach.Request, breach); err != nil {
+			errs = append(errs, err)
+			if _, err := db.NewUpdate().
+				Model(breach).
+				Set("escalated_at = NULL").
+				WherePK().
+				Exec(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
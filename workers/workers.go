@@ -0,0 +1,54 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/logging"
+	"github.com/uptrace/bun"
+)
+
+// Start launches the background workers. They stop when ctx is cancelled.
+// Setting a worker's interval to 0 disables it.
+func Start(ctx context.Context, db *bun.DB) {
+	start(ctx, "sla_checker", config.Duration("SLA_CHECK_INTERVAL", time.Minute), func(ctx context.Context) error {
+		return CheckSLAs(ctx, db)
+	})
+
+	lead := config.Duration("SCHEDULE_LEAD_TIME", 24*time.Hour)
+	start(ctx, "scheduler", config.Duration("SCHEDULE_INTERVAL", 5*time.Minute), func(ctx context.Context) error {
+		return RunSchedules(ctx, db, lead)
+	})
+
+	start(ctx, "confirmation_auto_accept", config.Duration("CONFIRMATION_CHECK_INTERVAL", 5*time.Minute), func(ctx context.Context) error {
+		return AutoAcceptConfirmations(ctx, db)
+	})
+}
+
+// start runs fn every interval in its own goroutine, logging failures and
+// carrying on.
+func start(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	log := slog.Default().With("worker", name)
+	if interval <= 0 {
+		log.Info("worker disabled")
+		return
+	}
+
+	ctx = logging.NewContext(ctx, log)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.Error("worker run failed", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,221 @@
+package workflow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+var (
+	// ErrNoConfirmation means the request has never been completed, so there
+	// is nothing to confirm or dispute.
+	ErrNoConfirmation = errors.New("request has no completion to confirm")
+	// ErrConfirmationAnswered means the latest completion was already
+	// confirmed or disputed, or it was auto-accepted and can only be
+	// disputed.
+	ErrConfirmationAnswered = errors.New("completion was already answered")
+	// ErrDisputeWindowEnded means the completion can no longer be disputed.
+	ErrDisputeWindowEnded = errors.New("dispute window has ended")
+)
+
+// ConfirmationAutoAcceptAfter is how long room members have to answer a
+// completion before it is accepted on their behalf.
+func ConfirmationAutoAcceptAfter() time.Duration {
+	return config.Duration("CONFIRMATION_AUTO_ACCEPT_AFTER", 72*time.Hour)
+}
+
+// DisputeWindow is how long after completion room members may dispute the
+// work, including after it was auto-accepted.
+func DisputeWindow() time.Duration {
+	return config.Duration("CONFIRMATION_DISPUTE_WINDOW", 7*24*time.Hour)
+}
+
+// openConfirmation asks the room members to confirm a request that has just
+// been completed.
+func openConfirmation(ctx context.Context, db bun.IDB, request *models.Request) error {
+	_, err := db.NewInsert().
+		Model(&models.RequestConfirmation{
+			RequestID: request.ID,
+			Status:    models.ConfirmationPending,
+		}).
+		Value("auto_accept_at", "now() + ? * interval '1 second'", int64(ConfirmationAutoAcceptAfter().Seconds())).
+		Value("dispute_until", "now() + ? * interval '1 second'", int64(DisputeWindow().Seconds())).
+		Exec(ctx)
+	return err
+}
+
+// LatestConfirmation loads the confirmation for request's most recent
+// completion, locking it when lock is set. It returns ErrNoConfirmation when
+// the request was never completed.
+func LatestConfirmation(ctx context.Context, db bun.IDB, requestID int, lock bool) (*models.RequestConfirmation, error) {
+	confirmation := new(models.RequestConfirmation)
+	query := db.NewSelect().
+		Model(confirmation).
+		Where("rcf.request_id = ?", requestID).
+		Order("rcf.completed_at DESC", "rcf.id DESC").
+		Limit(1)
+	if lock {
+		query = query.For("UPDATE")
+	}
+	if err := query.Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoConfirmation
+		}
+		return nil, err
+	}
+	return confirmation, nil
+}
+
+// Confirm records actor accepting the latest completion of request with a
+// rating from 1 to 5 and optional feedback. Callers hold the request row
+// lock.
+func Confirm(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, rating int, feedback *string) (*models.RequestConfirmation, error) {
+	if request.Status != models.RequestStatusCompleted {
+		return nil, ErrNoConfirmation
+	}
+	confirmation, err := LatestConfirmation(ctx, db, request.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	if confirmation.Status != models.ConfirmationPending {
+		return nil, ErrConfirmationAnswered
+	}
+
+	if _, err := db.NewUpdate().
+		Model(confirmation).
+		Set("status = ?", models.ConfirmationConfirmed).
+		Set("responded_by = ?", actor.ID).
+		Set("responded_at = now()").
+		Set("rating = ?", rating).
+		Set("feedback = ?", feedback).
+		WherePK().
+		Returning("*").
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return confirmation, RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventConfirmed, "confirmation",
+		confirmationValue(models.ConfirmationPending), confirmationValue(models.ConfirmationConfirmed)))
+}
+
+// Dispute records actor rejecting the latest completion of request and
+// reopens it with a fresh SLA deadline: back to assigned when it has an
+// assignee, otherwise to active. A pending or auto-accepted completion can be
+// disputed until its dispute window ends. Callers hold the request row lock.
+func Dispute(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, feedback *string) (*models.RequestConfirmation, error) {
+	if request.Status != models.RequestStatusCompleted {
+		return nil, ErrNoConfirmation
+	}
+	confirmation, err := LatestConfirmation(ctx, db, request.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	if confirmation.Status != models.ConfirmationPending && confirmation.Status != models.ConfirmationAutoAccepted {
+		return nil, ErrConfirmationAnswered
+	}
+
+	status := models.RequestStatusActive
+	if request.AssigneeID != nil {
+		status = models.RequestStatusAssigned
+	}
+	if request.OneActive {
+		exists, err := db.NewSelect().
+			Model((*models.Request)(nil)).
+			Where("room_id = ?", request.RoomID).
+			Where("type = ?", request.Type).
+			Where("status IN (?)", bun.In(models.OpenRequestStatuses)).
+			Where("one_active").
+			Exists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrOpenRequestExists
+		}
+	}
+
+	previous := confirmation.Status
+	res, err := db.NewUpdate().
+		Model(confirmation).
+		Set("status = ?", models.ConfirmationDisputed).
+		Set("responded_by = ?", actor.ID).
+		Set("responded_at = now()").
+		Set("feedback = ?", feedback).
+		WherePK().
+		Where("dispute_until > now()").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return nil, ErrDisputeWindowEnded
+	}
+
+	if err := RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventDisputed, "confirmation",
+		confirmationValue(previous), confirmationValue(models.ConfirmationDisputed))); err != nil {
+		return nil, err
+	}
+	return confirmation, reopen(ctx, db, request, actor, status)
+}
+
+// reopen moves a disputed request from completed back to status through its
+// reopen transition. The SLA clock restarts from now under the policy for the
+// request's type and priority, and any at-risk or breached flag is cleared.
+func reopen(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, status models.RequestStatus) error {
+	from := request.Status
+	if err := checkTransition(actor.Role, from, status, true); err != nil {
+		return err
+	}
+
+	res, err := db.NewUpdate().
+		Model(request).
+		Set("status = ?", status).
+		Set("sla_due_at = "+slaDeadlineFrom("now()")).
+		Set("sla_at_risk_at = NULL").
+		Set("sla_breached_at = NULL").
+		Where("req.id = ?", request.ID).
+		Where("req.status = ?", from).
+		Returning("status, sla_due_at, sla_at_risk_at, sla_breached_at, updated_at").
+		Exec(ctx)
+	if err != nil {
+		if isOpenRequestConflict(err) {
+			return ErrOpenRequestExists
+		}
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrStaleStatus
+	}
+
+	return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventStatusChanged, "status",
+		statusValue(from), statusValue(status)))
+}
+
+// AutoAccept accepts a pending confirmation nobody answered in time and
+// records it in the request's history. Callers hold the confirmation row
+// lock.
+func AutoAccept(ctx context.Context, db bun.IDB, confirmation *models.RequestConfirmation) error {
+	if _, err := db.NewUpdate().
+		Model(confirmation).
+		Set("status = ?", models.ConfirmationAutoAccepted).
+		Set("responded_at = now()").
+		WherePK().
+		Returning("status, responded_at").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	return RecordEvent(ctx, db, newEvent(confirmation.RequestID, nil, models.RequestEventConfirmed, "confirmation",
+		confirmationValue(models.ConfirmationPending), confirmationValue(models.ConfirmationAutoAccepted)))
+}
+
+func confirmationValue(status models.ConfirmationStatus) *string {
+	value := string(status)
+	return &value
+}
@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// RecordEvent appends an entry to a request's history. Pass the transaction
+// making the change so the entry commits or rolls back with it.
+func RecordEvent(ctx context.Context, db bun.IDB, event *models.RequestEvent) error {
+	_, err := db.NewInsert().Model(event).Exec(ctx)
+	return err
+}
+
+// newEvent builds a history entry for a change to field. A nil actor marks a
+// system change.
+func newEvent(requestID int, actor *models.User, eventType models.RequestEventType, field string, oldValue, newValue *string) *models.RequestEvent {
+	event := &models.RequestEvent{
+		RequestID: requestID,
+		Type:      eventType,
+		Field:     &field,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+	}
+	if actor != nil {
+		event.ActorID = &actor.ID
+	}
+	return event
+}
+
+func statusValue(status models.RequestStatus) *string {
+	value := string(status)
+	return &value
+}
+
+func priorityValue(priority models.RequestPriority) *string {
+	value := string(priority)
+	return &value
+}
+
+func userValue(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	value := id.String()
+	return &value
+}
@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+// ErrUnknownRequestType means no request type has the given name, or it has
+// been retired and new requests cannot use it.
+var ErrUnknownRequestType = errors.New("unknown request type")
+
+// NormalizeRequestType trims and lower-cases a request type name from user
+// input.
+func NormalizeRequestType(value string) models.RequestType {
+	return models.RequestType(strings.ToLower(strings.TrimSpace(value)))
+}
+
+// LookupRequestType loads the request type called name. Retired types are
+// only returned when includeInactive is set, for looking up existing
+// requests; filing new ones needs an active type.
+func LookupRequestType(ctx context.Context, db bun.IDB, name models.RequestType, includeInactive bool) (*models.RequestTypeConfig, error) {
+	config := new(models.RequestTypeConfig)
+	query := db.NewSelect().
+		Model(config).
+		Where("rtype.name = ?", name)
+	if !includeInactive {
+		query = query.Where("rtype.active")
+	}
+	if err := query.Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnknownRequestType
+		}
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,234 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+var (
+	// ErrStaleStatus means the request changed status while the caller was
+	// deciding what to do with it.
+	ErrStaleStatus = errors.New("request status changed concurrently")
+	// ErrAssigneeRequired means the request cannot enter assigned without
+	// someone to assign it to; use Assign.
+	ErrAssigneeRequired = errors.New("assign the request to move it to assigned")
+	// ErrNotAssignable means the request is closed and can no longer be
+	// assigned.
+	ErrNotAssignable = errors.New("request is closed")
+	// ErrRequestClosed means the request is completed or cancelled and can no
+	// longer be edited.
+	ErrRequestClosed = errors.New("request is closed")
+	// ErrOpenRequestExists means the room already has an open request of the
+	// same type.
+	ErrOpenRequestExists = errors.New("open request already exists for this room and type")
+)
+
+// SetStatus moves request to status on behalf of actor, checking the
+// transition table first, and records the change in the request's history.
+// Completing a request asks its room members to confirm the work. request is
+// updated in place. Callers should run it inside a transaction
+// with the request row locked.
+func SetStatus(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, status models.RequestStatus) error {
+	if err := CheckTransition(actor.Role, request.Status, status); err != nil {
+		return err
+	}
+	if status == models.RequestStatusAssigned {
+		return ErrAssigneeRequired
+	}
+
+	from := request.Status
+	res, err := db.NewUpdate().
+		Model(request).
+		Set("status = ?", status).
+		Where("id = ?", request.ID).
+		Where("status = ?", from).
+		Returning("status, updated_at").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrStaleStatus
+	}
+
+	if err := RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventStatusChanged, "status", statusValue(from), statusValue(status))); err != nil {
+		return err
+	}
+	if status == models.RequestStatusCompleted {
+		return openConfirmation(ctx, db, request)
+	}
+	return nil
+}
+
+// Assign gives request to assignee on behalf of actor. A request that nobody
+// has picked up yet moves to assigned; one already being worked keeps its
+// status and only changes hands. Callers check that assignee may work the
+// request and run it inside a transaction with the row locked.
+func Assign(ctx context.Context, db bun.IDB, request *models.Request, actor, assignee *models.User) error {
+	if !request.Status.IsOpen() {
+		return ErrNotAssignable
+	}
+
+	from, previousAssignee := request.Status, request.AssigneeID
+	status := from
+	if status == models.RequestStatusActive {
+		if err := CheckTransition(actor.Role, status, models.RequestStatusAssigned); err != nil {
+			return err
+		}
+		status = models.RequestStatusAssigned
+	}
+
+	res, err := db.NewUpdate().
+		Model(request).
+		Set("status = ?", status).
+		Set("assignee_id = ?", assignee.ID).
+		Set("assigned_at = now()").
+		Where("id = ?", request.ID).
+		Where("status = ?", from).
+		Returning("status, assignee_id, assigned_at, updated_at").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return ErrStaleStatus
+	}
+	request.Assignee = assignee
+
+	if err := RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventAssigned, "assignee_id", userValue(previousAssignee), userValue(&assignee.ID))); err != nil {
+		return err
+	}
+	if status != from {
+		return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventStatusChanged, "status", statusValue(from), statusValue(status)))
+	}
+	return nil
+}
+
+// Create files request on behalf of actor, along with any preferred time
+// windows set on it, and records its creation. The type must be active; its
+// default priority applies when no priority was suggested, and when it allows
+// one active request per room a second open one is refused. A nil actor marks
+// a request raised by the system.
+func Create(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User) error {
+	config, err := LookupRequestType(ctx, db, request.Type, false)
+	if err != nil {
+		return err
+	}
+
+	if config.OneActivePerRoom {
+		exists, err := db.NewSelect().
+			Model((*models.Request)(nil)).
+			Where("room_id = ?", request.RoomID).
+			Where("type = ?", request.Type).
+			Where("status IN (?)", bun.In(models.OpenRequestStatuses)).
+			Where("one_active").
+			Exists(ctx)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrOpenRequestExists
+		}
+	}
+
+	request.Status = models.RequestStatusActive
+	request.OneActive = config.OneActivePerRoom
+	if request.SuggestedPriority == "" {
+		request.SuggestedPriority = config.DefaultPriority
+	}
+	if request.Priority == "" {
+		request.Priority = request.SuggestedPriority
+	}
+	if _, err := db.NewInsert().Model(request).Exec(ctx); err != nil {
+		if isOpenRequestConflict(err) {
+			return ErrOpenRequestExists
+		}
+		return err
+	}
+	if err := insertTimeWindows(ctx, db, request.ID, request.TimeWindows); err != nil {
+		return err
+	}
+	if err := updateSLADeadline(ctx, db, request); err != nil {
+		return err
+	}
+	if err := db.NewSelect().Model(request).WherePK().Scan(ctx); err != nil {
+		return err
+	}
+	request.TypeConfig = config
+
+	return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventCreated, "status", nil, statusValue(request.Status)))
+}
+
+// SetDescription replaces the description of an open request on behalf of
+// actor and records the edit. Nothing is recorded when the text is unchanged.
+func SetDescription(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, description *string) error {
+	if !request.Status.IsOpen() {
+		return ErrRequestClosed
+	}
+	var current, next string
+	if request.Description != nil {
+		current = *request.Description
+	}
+	if description != nil {
+		next = *description
+	}
+	if current == next {
+		return nil
+	}
+
+	previous := request.Description
+	if _, err := db.NewUpdate().
+		Model(request).
+		Set("description = ?", description).
+		Where("id = ?", request.ID).
+		Returning("description, updated_at").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventEdited, "description", previous, description))
+}
+
+// SetPriority confirms or changes the priority of an open request on behalf
+// of a staff member and records any change. A new priority moves the SLA
+// deadline to that of its policy. The reporter's suggestion is kept in
+// SuggestedPriority.
+func SetPriority(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, priority models.RequestPriority) error {
+	if !request.Status.IsOpen() {
+		return ErrRequestClosed
+	}
+
+	previous := request.Priority
+	if _, err := db.NewUpdate().
+		Model(request).
+		Set("priority = ?", priority).
+		Set("priority_confirmed_by = ?", actor.ID).
+		Set("priority_confirmed_at = now()").
+		Where("id = ?", request.ID).
+		Returning("priority, priority_confirmed_by, priority_confirmed_at, updated_at").
+		Exec(ctx); err != nil {
+		return err
+	}
+	if previous == priority {
+		return nil
+	}
+	if err := updateSLADeadline(ctx, db, request); err != nil {
+		return err
+	}
+
+	return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventPriority, "priority", priorityValue(previous), priorityValue(priority)))
+}
+
+// isOpenRequestConflict reports whether err is a violation of the
+// one-open-request-per-room-and-type index, raised when a concurrent insert
+// wins the race past the check in Create.
+func isOpenRequestConflict(err error) bool {
+	var pgErr pgdriver.Error
+	return errors.As(err, &pgErr) &&
+		pgErr.Field('C') == "23505" &&
+		pgErr.Field('n') == "unique_open_request_per_room_type"
+}
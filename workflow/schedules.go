@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/adii2ma/dbms-backend/recurrence"
+	"github.com/uptrace/bun"
+)
+
+// ScheduleRule parses a schedule's recurrence, anchored at its start date in
+// the service time zone.
+func ScheduleRule(schedule *models.RequestSchedule) (recurrence.Rule, error) {
+	start := time.Date(schedule.StartsOn.Year(), schedule.StartsOn.Month(), schedule.StartsOn.Day(), 0, 0, 0, 0, ServiceLocation())
+	return recurrence.Parse(schedule.Recurrence, start)
+}
+
+// ScheduleWindow returns the visit window of the occurrence on date.
+func ScheduleWindow(schedule *models.RequestSchedule, date time.Time) (*models.RequestTimeWindow, error) {
+	opens, err := ParseClock(schedule.WindowStart)
+	if err != nil {
+		return nil, err
+	}
+	closes, err := ParseClock(schedule.WindowEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RequestTimeWindow{
+		StartsAt: atClock(date, opens),
+		EndsAt:   atClock(date, closes),
+	}, nil
+}
+
+// NextScheduleRun returns the start of the first occurrence window that
+// begins after after, or nil once the rule has ended.
+func NextScheduleRun(schedule *models.RequestSchedule, after time.Time) (*time.Time, error) {
+	rule, err := ScheduleRule(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	from := after.In(ServiceLocation())
+	for {
+		date, ok := rule.Next(from)
+		if !ok {
+			return nil, nil
+		}
+		window, err := ScheduleWindow(schedule, date)
+		if err != nil {
+			return nil, err
+		}
+		if window.StartsAt.After(after) {
+			start := window.StartsAt.UTC()
+			return &start, nil
+		}
+		from = date.AddDate(0, 0, 1)
+	}
+}
+
+// RunSchedule files the request for a schedule's next occurrence and moves
+// NextRunAt on to the one after. Occurrences whose window has already ended
+// are skipped, as are occurrences while the room still has an open request of
+// the same type and occurrences after the type was retired. It returns the
+// request filed, if any. Callers lock the schedule row.
+func RunSchedule(ctx context.Context, tx bun.Tx, schedule *models.RequestSchedule) (*models.Request, error) {
+	if schedule.NextRunAt == nil {
+		return nil, nil
+	}
+
+	window, err := ScheduleWindow(schedule, schedule.NextRunAt.In(ServiceLocation()))
+	if err != nil {
+		return nil, err
+	}
+
+	var request *models.Request
+	if window.EndsAt.After(time.Now()) {
+		request = &models.Request{
+			UserID:            schedule.CreatedBy,
+			RoomID:            schedule.RoomID,
+			Type:              schedule.Type,
+			Description:       schedule.Description,
+			SuggestedPriority: schedule.Priority,
+			ScheduleID:        &schedule.ID,
+			TimeWindows:       []*models.RequestTimeWindow{window},
+		}
+		// A savepoint keeps a clash with the one-open-request index from
+		// aborting the caller's transaction.
+		err := tx.RunInTx(ctx, nil, func(ctx context.Context, sp bun.Tx) error {
+			return Create(ctx, sp, request, nil)
+		})
+		if errors.Is(err, ErrOpenRequestExists) || errors.Is(err, ErrUnknownRequestType) {
+			request = nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	next, err := NextScheduleRun(schedule, *schedule.NextRunAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.NewUpdate().
+		Model(schedule).
+		Set("next_run_at = ?", next).
+		Set("last_run_at = now()").
+		WherePK().
+		Returning("next_run_at, last_run_at, updated_at").
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// atClock returns the time offset from midnight on date's day, in date's
+// location. Building it from fields keeps wall-clock times right across DST
+// changes.
+func atClock(date time.Time, offset time.Duration) time.Time {
+	hours := int(offset / time.Hour)
+	minutes := int(offset % time.Hour / time.Minute)
+	seconds := int(offset % time.Minute / time.Second)
+	return time.Date(date.Year(), date.Month(), date.Day(), hours, minutes, seconds, 0, date.Location())
+}
@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+// updateSLADeadline sets request's SLA deadline from the policy for its type
+// and priority, or the type's default SLA when there is none, measured from
+// when it was filed, and clears any at-risk flag so the checker re-evaluates
+// it. Requests neither covers get no deadline.
+func updateSLADeadline(ctx context.Context, db bun.IDB, request *models.Request) error {
+	_, err := db.NewUpdate().
+		Model(request).
+		Set("sla_due_at = "+slaDeadlineFrom("req.created_at")).
+		Set("sla_at_risk_at = NULL").
+		Where("req.id = ?", request.ID).
+		Returning("sla_due_at, sla_at_risk_at, updated_at").
+		Exec(ctx)
+	return err
+}
+
+// slaDeadlineFrom is the SQL for a request's SLA deadline measured from start,
+// an SQL expression, following the same rules as updateSLADeadline.
+func slaDeadlineFrom(start string) string {
+	return start + ` + (
+		SELECT COALESCE(p.resolve_within_minutes, t.sla_resolve_within_minutes)
+		FROM request_types t
+		LEFT JOIN sla_policies p ON p.type = t.name AND p.priority = req.priority
+		WHERE t.name = req.type
+	) * interval '1 minute'`
+}
+
+// MarkSLAAtRisk flags an open request whose deadline is near and records it
+// in the request's history. Callers hold the row lock.
+func MarkSLAAtRisk(ctx context.Context, db bun.IDB, request *models.Request) error {
+	if _, err := db.NewUpdate().
+		Model(request).
+		Set("sla_at_risk_at = now()").
+		Where("req.id = ?", request.ID).
+		Returning("sla_at_risk_at, updated_at").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	return RecordEvent(ctx, db, &models.RequestEvent{
+		RequestID: request.ID,
+		Type:      models.RequestEventSLAAtRisk,
+	})
+}
+
+// RecordSLABreach marks request as having missed its deadline, stores the
+// breach and records it in the request's history. Callers hold the row lock
+// and escalate the returned breach once the transaction commits.
+func RecordSLABreach(ctx context.Context, db bun.IDB, request *models.Request) (*models.SLABreach, error) {
+	if _, err := db.NewUpdate().
+		Model(request).
+		Set("sla_breached_at = now()").
+		Where("req.id = ?", request.ID).
+		Returning("sla_breached_at, updated_at").
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	breach := &models.SLABreach{
+		RequestID: request.ID,
+		DueAt:     *request.SLADueAt,
+	}
+	if _, err := db.NewInsert().Model(breach).Returning("*").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	due := request.SLADueAt.UTC().Format("2006-01-02T15:04:05Z")
+	if err := RecordEvent(ctx, db, &models.RequestEvent{
+		RequestID: request.ID,
+		Type:      models.RequestEventSLABreached,
+		OldValue:  &due,
+	}); err != nil {
+		return nil, err
+	}
+	return breach, nil
+}
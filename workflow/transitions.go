@@ -0,0 +1,96 @@
+// Package workflow holds the request lifecycle: which status changes are
+// allowed, who may make them, and the code that applies them.
+package workflow
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/adii2ma/dbms-backend/models"
+)
+
+var (
+	// ErrInvalidTransition means no role may move a request between the two
+	// statuses.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrTransitionForbidden means the transition exists but the role may not
+	// make it.
+	ErrTransitionForbidden = errors.New("role may not make this status transition")
+)
+
+// Transition is one allowed status change and the roles that may make it.
+// Reopen transitions are only made by disputing a completion, never through
+// SetStatus.
+type Transition struct {
+	From   models.RequestStatus
+	To     models.RequestStatus
+	Roles  []models.Role
+	Reopen bool
+}
+
+var (
+	workers     = []models.Role{models.RoleCleaner, models.RoleTechnician, models.RoleWarden, models.RoleAdmin}
+	technicians = []models.Role{models.RoleTechnician, models.RoleWarden, models.RoleAdmin}
+	reporters   = []models.Role{models.RoleResident, models.RoleWarden, models.RoleAdmin}
+	supervisors = []models.Role{models.RoleWarden, models.RoleAdmin}
+	residents   = []models.Role{models.RoleResident}
+)
+
+// Transitions is the complete request lifecycle. A status change not listed
+// here is rejected. Moves into assigned happen through Assign, which also
+// records who the request is assigned to.
+var Transitions = []Transition{
+	{From: models.RequestStatusActive, To: models.RequestStatusAssigned, Roles: workers},
+	{From: models.RequestStatusActive, To: models.RequestStatusCompleted, Roles: workers},
+	{From: models.RequestStatusActive, To: models.RequestStatusCancelled, Roles: reporters},
+
+	{From: models.RequestStatusAssigned, To: models.RequestStatusInProgress, Roles: workers},
+	{From: models.RequestStatusAssigned, To: models.RequestStatusCompleted, Roles: workers},
+	{From: models.RequestStatusAssigned, To: models.RequestStatusCancelled, Roles: reporters},
+
+	{From: models.RequestStatusInProgress, To: models.RequestStatusOnHold, Roles: workers},
+	{From: models.RequestStatusInProgress, To: models.RequestStatusAwaitingParts, Roles: technicians},
+	{From: models.RequestStatusInProgress, To: models.RequestStatusCompleted, Roles: workers},
+	{From: models.RequestStatusInProgress, To: models.RequestStatusCancelled, Roles: supervisors},
+
+	{From: models.RequestStatusOnHold, To: models.RequestStatusInProgress, Roles: workers},
+	{From: models.RequestStatusOnHold, To: models.RequestStatusCancelled, Roles: reporters},
+
+	{From: models.RequestStatusAwaitingParts, To: models.RequestStatusInProgress, Roles: technicians},
+	{From: models.RequestStatusAwaitingParts, To: models.RequestStatusCancelled, Roles: reporters},
+
+	{From: models.RequestStatusCompleted, To: models.RequestStatusActive, Roles: residents, Reopen: true},
+	{From: models.RequestStatusCompleted, To: models.RequestStatusAssigned, Roles: residents, Reopen: true},
+}
+
+// CheckTransition reports whether role may move a request from one status to
+// another.
+func CheckTransition(role models.Role, from, to models.RequestStatus) error {
+	return checkTransition(role, from, to, false)
+}
+
+// checkTransition is CheckTransition over either the reopen transitions or
+// the rest.
+func checkTransition(role models.Role, from, to models.RequestStatus, reopen bool) error {
+	for _, t := range Transitions {
+		if t.From != from || t.To != to || t.Reopen != reopen {
+			continue
+		}
+		if !slices.Contains(t.Roles, role) {
+			return ErrTransitionForbidden
+		}
+		return nil
+	}
+	return ErrInvalidTransition
+}
+
+// NextStatuses lists the statuses role may move a request in from to.
+func NextStatuses(role models.Role, from models.RequestStatus) []models.RequestStatus {
+	next := []models.RequestStatus{}
+	for _, t := range Transitions {
+		if t.From == from && !t.Reopen && slices.Contains(t.Roles, role) {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
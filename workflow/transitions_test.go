@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/adii2ma/dbms-backend/models"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     models.Role
+		from, to models.RequestStatus
+		want     error
+	}{
+		{"cleaner starts work", models.RoleCleaner, models.RequestStatusAssigned, models.RequestStatusInProgress, nil},
+		{"cleaner completes", models.RoleCleaner, models.RequestStatusInProgress, models.RequestStatusCompleted, nil},
+		{"resident cancels active", models.RoleResident, models.RequestStatusActive, models.RequestStatusCancelled, nil},
+		{"resident cannot complete", models.RoleResident, models.RequestStatusActive, models.RequestStatusCompleted, ErrTransitionForbidden},
+		{"cleaner cannot await parts", models.RoleCleaner, models.RequestStatusInProgress, models.RequestStatusAwaitingParts, ErrTransitionForbidden},
+		{"technician awaits parts", models.RoleTechnician, models.RequestStatusInProgress, models.RequestStatusAwaitingParts, nil},
+		{"resident cannot cancel in progress", models.RoleResident, models.RequestStatusInProgress, models.RequestStatusCancelled, ErrTransitionForbidden},
+		{"warden cancels in progress", models.RoleWarden, models.RequestStatusInProgress, models.RequestStatusCancelled, nil},
+		{"no skipping back to active", models.RoleAdmin, models.RequestStatusInProgress, models.RequestStatusActive, ErrInvalidTransition},
+		{"cancelled is final", models.RoleAdmin, models.RequestStatusCancelled, models.RequestStatusActive, ErrInvalidTransition},
+		{"reopen is not a status change", models.RoleResident, models.RequestStatusCompleted, models.RequestStatusActive, ErrInvalidTransition},
+		{"same status", models.RoleAdmin, models.RequestStatusActive, models.RequestStatusActive, ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckTransition(tt.role, tt.from, tt.to); !errors.Is(err, tt.want) {
+				t.Fatalf("CheckTransition(%s, %s, %s) = %v, want %v", tt.role, tt.from, tt.to, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReopenTransitions(t *testing.T) {
+	tests := []struct {
+		role models.Role
+		to   models.RequestStatus
+		want error
+	}{
+		{models.RoleResident, models.RequestStatusActive, nil},
+		{models.RoleResident, models.RequestStatusAssigned, nil},
+		{models.RoleWarden, models.RequestStatusActive, ErrTransitionForbidden},
+		{models.RoleResident, models.RequestStatusInProgress, ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		if err := checkTransition(tt.role, models.RequestStatusCompleted, tt.to, true); !errors.Is(err, tt.want) {
+			t.Errorf("reopen %s to %s = %v, want %v", tt.role, tt.to, err, tt.want)
+		}
+	}
+}
+
+func TestNextStatuses(t *testing.T) {
+	tests := []struct {
+		role models.Role
+		from models.RequestStatus
+		want []models.RequestStatus
+	}{
+		{models.RoleCleaner, models.RequestStatusInProgress, []models.RequestStatus{models.RequestStatusOnHold, models.RequestStatusCompleted}},
+		{models.RoleResident, models.RequestStatusActive, []models.RequestStatus{models.RequestStatusCancelled}},
+		{models.RoleResident, models.RequestStatusCompleted, []models.RequestStatus{}},
+		{models.RoleAdmin, models.RequestStatusCancelled, []models.RequestStatus{}},
+	}
+
+	for _, tt := range tests {
+		if got := NextStatuses(tt.role, tt.from); !slices.Equal(got, tt.want) {
+			t.Errorf("NextStatuses(%s, %s) = %v, want %v", tt.role, tt.from, got, tt.want)
+		}
+	}
+}
+
+// TestTransitionsAreUnique guards against a duplicate entry silently
+// shadowing another's roles.
+func TestTransitionsAreUnique(t *testing.T) {
+	seen := map[[2]models.RequestStatus]bool{}
+	for _, tr := range Transitions {
+		key := [2]models.RequestStatus{tr.From, tr.To}
+		if seen[key] {
+			t.Errorf("duplicate transition %s -> %s", tr.From, tr.To)
+		}
+		seen[key] = true
+		if tr.From == tr.To {
+			t.Errorf("transition %s -> %s does not change status", tr.From, tr.To)
+		}
+		if len(tr.Roles) == 0 {
+			t.Errorf("transition %s -> %s has no roles", tr.From, tr.To)
+		}
+	}
+}
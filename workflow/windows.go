@@ -0,0 +1,238 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adii2ma/dbms-backend/config"
+	"github.com/adii2ma/dbms-backend/models"
+	"github.com/uptrace/bun"
+)
+
+// MaxTimeWindows is how many preferred visit windows a request may carry.
+const MaxTimeWindows = 5
+
+// WindowError explains why a preferred time window was rejected. Index is the
+// position of the offending window, or -1 when the list as a whole is at
+// fault.
+type WindowError struct {
+	Index  int
+	Reason string
+}
+
+func (e *WindowError) Error() string {
+	if e.Index < 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("time window %d: %s", e.Index+1, e.Reason)
+}
+
+var (
+	serviceLocationOnce sync.Once
+	serviceLocation     *time.Location
+)
+
+// ServiceLocation is the time zone service hours are written in, from
+// SERVICE_TIMEZONE. It falls back to UTC when the zone is unknown.
+func ServiceLocation() *time.Location {
+	serviceLocationOnce.Do(func() {
+		name := config.String("SERVICE_TIMEZONE", "UTC")
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			slog.Warn("unknown SERVICE_TIMEZONE; using UTC", "timezone", name, "error", err)
+			loc = time.UTC
+		}
+		serviceLocation = loc
+	})
+	return serviceLocation
+}
+
+// ServiceHours returns the service hours configured for block. Blocks without
+// any use SERVICE_HOURS_DEFAULT (08:00-18:00 unless set) every day.
+func ServiceHours(ctx context.Context, db bun.IDB, block string) ([]models.BlockServiceHours, error) {
+	var hours []models.BlockServiceHours
+	if err := db.NewSelect().
+		Model(&hours).
+		Where("lower(bsh.block) = lower(?)", strings.TrimSpace(block)).
+		Order("bsh.weekday ASC", "bsh.opens_at ASC").
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	if len(hours) > 0 {
+		return hours, nil
+	}
+
+	opens, closes := "08:00", "18:00"
+	if value := config.String("SERVICE_HOURS_DEFAULT", ""); value != "" {
+		from, to, ok := strings.Cut(value, "-")
+		o, errOpen := ParseClock(from)
+		c, errClose := ParseClock(to)
+		if ok && errOpen == nil && errClose == nil && o < c {
+			opens, closes = strings.TrimSpace(from), strings.TrimSpace(to)
+		} else {
+			slog.Warn("invalid SERVICE_HOURS_DEFAULT; using 08:00-18:00", "value", value)
+		}
+	}
+	for day := range 7 {
+		hours = append(hours, models.BlockServiceHours{Block: block, Weekday: day, OpensAt: opens, ClosesAt: closes})
+	}
+	return hours, nil
+}
+
+// ParseClock parses a wall-clock time such as "08:00" or "08:00:00" into the
+// offset from midnight.
+func ParseClock(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	t, err := time.Parse("15:04:05", value)
+	if err != nil {
+		t, err = time.Parse("15:04", value)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+// ValidateTimeWindows checks that each window lies in the future and inside
+// one span of the block's service hours on a single day.
+func ValidateTimeWindows(ctx context.Context, db bun.IDB, block string, windows []*models.RequestTimeWindow) error {
+	if len(windows) > MaxTimeWindows {
+		return &WindowError{Index: -1, Reason: fmt.Sprintf("at most %d time windows are allowed", MaxTimeWindows)}
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	hours, err := ServiceHours(ctx, db, block)
+	if err != nil {
+		return err
+	}
+
+	loc := ServiceLocation()
+	now := time.Now()
+	for i, window := range windows {
+		if !window.StartsAt.Before(window.EndsAt) {
+			return &WindowError{Index: i, Reason: "starts_at must be before ends_at"}
+		}
+		if !window.EndsAt.After(now) {
+			return &WindowError{Index: i, Reason: "window is in the past"}
+		}
+
+		start, end := window.StartsAt.In(loc), window.EndsAt.In(loc)
+		if start.YearDay() != end.YearDay() || start.Year() != end.Year() {
+			return &WindowError{Index: i, Reason: "window must start and end on the same day"}
+		}
+		if !withinServiceHours(hours, start, end) {
+			return &WindowError{Index: i, Reason: "window falls outside the block's service hours"}
+		}
+	}
+	return nil
+}
+
+func withinServiceHours(hours []models.BlockServiceHours, start, end time.Time) bool {
+	midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	from, to := start.Sub(midnight), end.Sub(midnight)
+	for _, span := range hours {
+		if span.Weekday != int(start.Weekday()) {
+			continue
+		}
+		opens, err := ParseClock(span.OpensAt)
+		if err != nil {
+			continue
+		}
+		closes, err := ParseClock(span.ClosesAt)
+		if err != nil {
+			continue
+		}
+		if opens <= from && to <= closes {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTimeWindows replaces the preferred visit windows of an open request on
+// behalf of actor and records the edit. Callers validate the windows first.
+func SetTimeWindows(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, windows []*models.RequestTimeWindow) error {
+	if !request.Status.IsOpen() {
+		return ErrRequestClosed
+	}
+
+	var previous []*models.RequestTimeWindow
+	if err := db.NewSelect().
+		Model(&previous).
+		Where("request_id = ?", request.ID).
+		Order("starts_at ASC").
+		Scan(ctx); err != nil {
+		return err
+	}
+	if _, err := db.NewDelete().
+		Model((*models.RequestTimeWindow)(nil)).
+		Where("request_id = ?", request.ID).
+		Exec(ctx); err != nil {
+		return err
+	}
+	if err := insertTimeWindows(ctx, db, request.ID, windows); err != nil {
+		return err
+	}
+	request.TimeWindows = windows
+
+	return RecordEvent(ctx, db, newEvent(request.ID, actor, models.RequestEventEdited, "time_windows", windowsValue(previous), windowsValue(windows)))
+}
+
+func insertTimeWindows(ctx context.Context, db bun.IDB, requestID int, windows []*models.RequestTimeWindow) error {
+	if len(windows) == 0 {
+		return nil
+	}
+	for _, window := range windows {
+		window.RequestID = requestID
+		window.StartsAt = window.StartsAt.UTC()
+		window.EndsAt = window.EndsAt.UTC()
+	}
+	_, err := db.NewInsert().Model(&windows).Returning("id").Exec(ctx)
+	return err
+}
+
+// windowsValue formats windows for the request history.
+func windowsValue(windows []*models.RequestTimeWindow) *string {
+	if len(windows) == 0 {
+		return nil
+	}
+	parts := make([]string, len(windows))
+	for i, window := range windows {
+		parts[i] = window.StartsAt.UTC().Format(time.RFC3339) + "/" + window.EndsAt.UTC().Format(time.RFC3339)
+	}
+	value := strings.Join(parts, ", ")
+	return &value
+}
+
+// RecordNoAccess logs that actor visited the room of an open request but
+// could not get in, and records it in the request's history.
+func RecordNoAccess(ctx context.Context, db bun.IDB, request *models.Request, actor *models.User, note *string) (*models.RequestAccessAttempt, error) {
+	if !request.Status.IsOpen() {
+		return nil, ErrRequestClosed
+	}
+
+	attempt := &models.RequestAccessAttempt{
+		RequestID: request.ID,
+		UserID:    &actor.ID,
+		Note:      note,
+	}
+	if _, err := db.NewInsert().Model(attempt).Returning("*").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := RecordEvent(ctx, db, &models.RequestEvent{
+		RequestID: request.ID,
+		ActorID:   &actor.ID,
+		Type:      models.RequestEventNoAccess,
+		NewValue:  note,
+	}); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}